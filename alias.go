@@ -0,0 +1,152 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+)
+
+// Aliases map a name to a list of arguments replacing the alias in the
+// command line. Arguments can contain placeholders: "$1" to "$N" are replaced
+// by the arguments following the alias, "$@" (which must be a separate
+// argument) by all of them, and "$$" by a literal dollar sign. Arguments
+// which are not referenced by any placeholder are appended to the expansion.
+
+var aliasPlaceholderRE = regexp.MustCompile(`\$(\$|@|[0-9]+)`)
+
+func ExpandAlias(alias, args []string) ([]string, error) {
+	var expansion []string
+
+	used := make([]bool, len(args))
+	allUsed := false
+
+	for _, word := range alias {
+		if word == "$@" {
+			expansion = append(expansion, args...)
+			allUsed = true
+			continue
+		}
+
+		var err error
+
+		expandedWord := aliasPlaceholderRE.ReplaceAllStringFunc(word,
+			func(s string) string {
+				switch s[1:] {
+				case "$":
+					return "$"
+				case "@":
+					err = fmt.Errorf("$@ must be a separate argument")
+					return ""
+				}
+
+				n, _ := strconv.Atoi(s[1:])
+				if n < 1 {
+					err = fmt.Errorf("invalid placeholder %s", s)
+					return ""
+				} else if n > len(args) {
+					err = fmt.Errorf("missing argument %d", n)
+					return ""
+				}
+
+				used[n-1] = true
+				return args[n-1]
+			})
+		if err != nil {
+			return nil, err
+		}
+
+		expansion = append(expansion, expandedWord)
+	}
+
+	if !allUsed {
+		for i, arg := range args {
+			if !used[i] {
+				expansion = append(expansion, arg)
+			}
+		}
+	}
+
+	return expansion, nil
+}
+
+func CheckAlias(alias []string) error {
+	for _, word := range alias {
+		if word == "$@" {
+			continue
+		}
+
+		for _, s := range aliasPlaceholderRE.FindAllString(word, -1) {
+			switch s[1:] {
+			case "$":
+			case "@":
+				return fmt.Errorf("$@ must be a separate argument")
+			default:
+				if n, _ := strconv.Atoi(s[1:]); n < 1 {
+					return fmt.Errorf("invalid placeholder %s", s)
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// expandCommandLineAlias replaces the alias used in the command line if there
+// is one. It must be called before parsing the command line. Aliases are
+// only expanded once, and cannot shadow builtin commands.
+func expandCommandLineAlias(aliases map[string][]string) {
+	if len(aliases) == 0 {
+		return
+	}
+
+	spec := registry.Spec
+
+	args := os.Args[1:]
+
+	i := spec.CommandNameIndex(args)
+	if i == -1 {
+		return
+	}
+
+	name := args[i]
+
+	if _, found := spec.Commands[name]; found {
+		return
+	}
+
+	alias, found := aliases[name]
+	if !found {
+		return
+	}
+
+	expansion, err := ExpandAlias(alias, args[i+1:])
+	if err != nil {
+		p.Fatal("cannot expand alias %q: %v", name, err)
+	}
+
+	newArgs := append([]string{}, os.Args[:i+1]...)
+	newArgs = append(newArgs, expansion...)
+
+	os.Args = newArgs
+}
+
+// readConfigAliases returns the aliases of the configuration file. It is used
+// before parsing the command line, and therefore before loading the
+// configuration: it does not create the configuration file and ignores
+// errors, which are reported when the configuration is loaded.
+func readConfigAliases() map[string][]string {
+	config := DefaultConfig()
+
+	filePath := ConfigPath()
+
+	if err := config.CheckPermissions(filePath); err != nil {
+		return nil
+	}
+
+	if err := config.LoadFile(filePath); err != nil {
+		return nil
+	}
+
+	return config.Aliases
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExpandAlias(t *testing.T) {
+	assert := assert.New(t)
+
+	expand := func(alias []string, args ...string) []string {
+		expansion, err := ExpandAlias(alias, args)
+		if !assert.NoError(err) {
+			return nil
+		}
+
+		return expansion
+	}
+
+	assert.Equal([]string{"list-pipelines", "--status", "failed"},
+		expand([]string{"list-pipelines", "--status", "failed"}))
+	assert.Equal([]string{"list-pipelines", "-p", "prod", "--limit", "5"},
+		expand([]string{"list-pipelines", "-p", "$1"}, "prod", "--limit", "5"))
+	assert.Equal([]string{"execute-command", "deploy", "env=prod", "x"},
+		expand([]string{"execute-command", "$2", "env=$1"}, "prod", "deploy",
+			"x"))
+	assert.Equal([]string{"a", "b", "c", "d"},
+		expand([]string{"a", "$@", "d"}, "b", "c"))
+	assert.Equal([]string{"a", "$1", "x"},
+		expand([]string{"a", "$$1"}, "x"))
+
+	_, err := ExpandAlias([]string{"a", "$2"}, []string{"x"})
+	assert.Error(err)
+
+	_, err = ExpandAlias([]string{"a", "x$@"}, []string{"x"})
+	assert.Error(err)
+}
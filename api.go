@@ -3,6 +3,8 @@ package main
 import (
 	"encoding/json"
 	"fmt"
+	"net/url"
+	"strconv"
 	"time"
 )
 
@@ -67,6 +69,32 @@ type Cursor struct {
 	Order  Order  `json:"order,omitempty"`
 }
 
+func (c *Cursor) Query() url.Values {
+	query := url.Values{}
+
+	if c.Before != "" {
+		query.Add("before", c.Before)
+	}
+
+	if c.After != "" {
+		query.Add("after", c.After)
+	}
+
+	if c.Size != 0 {
+		query.Add("size", strconv.FormatUint(uint64(c.Size), 10))
+	}
+
+	if c.Sort != "" {
+		query.Add("sort", c.Sort)
+	}
+
+	if c.Order != "" {
+		query.Add("order", string(c.Order))
+	}
+
+	return query
+}
+
 type ProjectPage struct {
 	Elements []*Project `json:"elements"`
 	Previous *Cursor    `json:"previous,omitempty"`
@@ -202,6 +230,10 @@ func (p *Pipeline) Duration() *time.Duration {
 	return &d
 }
 
+func (p *Pipeline) ParseEventTime() (time.Time, error) {
+	return time.Parse(time.RFC3339, p.EventTime)
+}
+
 type Pipelines []*Pipeline
 
 func (ps Pipelines) ProjectIds() []string {
@@ -246,6 +278,15 @@ func (t *Task) Duration() *time.Duration {
 	return &d
 }
 
+func (t *Task) IsFinished() bool {
+	switch t.Status {
+	case "successful", "failed", "aborted":
+		return true
+	}
+
+	return false
+}
+
 type Tasks []*Task
 
 type NewEvent struct {
@@ -271,6 +312,12 @@ type Event struct {
 
 type Events []*Event
 
+type EventPage struct {
+	Elements Events  `json:"elements"`
+	Previous *Cursor `json:"previous,omitempty"`
+	Next     *Cursor `json:"next,omitempty"`
+}
+
 type CommandExecutionInput struct {
 	Parameters map[string]interface{} `json:"parameters"`
 }
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+func ReadAPIKeyFile(filePath string) (string, error) {
+	if strings.HasPrefix(filePath, "~/") {
+		homePath, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("cannot locate user home directory: %w",
+				err)
+		}
+
+		filePath = homePath + filePath[1:]
+	}
+
+	if err := app.Config.CheckPermissions(filePath); err != nil {
+		return "", err
+	}
+
+	data, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		return "", fmt.Errorf("cannot read %s: %w", filePath, err)
+	}
+
+	key := strings.TrimSpace(string(data))
+	if key == "" {
+		return "", fmt.Errorf("%s is empty", filePath)
+	}
+
+	return key, nil
+}
+
+func RunAPIKeyCommand(command string) (string, error) {
+	var stdout bytes.Buffer
+
+	// The command must not consume the standard input of evcli, which
+	// may be used by the command being executed (e.g. "-" arguments).
+	cmd := exec.Command("/bin/sh", "-c", command)
+	cmd.Stdin = nil
+	cmd.Stdout = &stdout
+	cmd.Stderr = os.Stderr
+
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("cannot run %q: %w", command, err)
+	}
+
+	// Helpers such as pass print the secret on the first line and may use
+	// other lines for metadata.
+	key := strings.TrimSpace(stdout.String())
+	if i := strings.IndexByte(key, '\n'); i >= 0 {
+		key = strings.TrimSpace(key[:i])
+	}
+
+	if key == "" {
+		return "", fmt.Errorf("command %q did not print any key", command)
+	}
+
+	return key, nil
+}
@@ -4,13 +4,9 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"io/ioutil"
 	"net/http"
 	"os"
-	"path"
-	"path/filepath"
 	"runtime"
-	"strconv"
 	"time"
 
 	"github.com/exograd/go-program"
@@ -26,16 +22,20 @@ type App struct {
 
 	HomePath string
 
+	Location *time.Location
+
 	projectIdOption   *string
 	projectNameOption *string
 }
 
-func NewApp(config *Config, client *Client) (*App, error) {
+func NewApp(config *Config, client *Client, httpClient *http.Client) (*App, error) {
 	a := &App{
 		Config: config,
 		Client: client,
 
-		HTTPClient: NewHTTPClient(),
+		HTTPClient: httpClient,
+
+		Location: time.Local,
 	}
 
 	a.UserAgent = fmt.Sprintf("evcli/%s (%s; %s)",
@@ -53,14 +53,10 @@ func NewApp(config *Config, client *Client) (*App, error) {
 }
 
 func (a *App) LoadAPIKey() {
-	if key := os.Getenv("EVENTLINE_API_KEY"); key != "" {
-		p.Debug(1, "using api key from EVENTLINE_API_KEY environment variable")
-		a.Client.APIKey = key
-		return
-	}
-
-	if key := a.Config.API.Key; key != "" {
-		p.Debug(1, "using api key from configuration")
+	key, err := a.readAPIKey()
+	if err != nil {
+		p.Fatal("%v", err)
+	} else if key != "" {
 		a.Client.APIKey = key
 		return
 	}
@@ -70,9 +66,62 @@ func (a *App) LoadAPIKey() {
 		"You can either edit the evcli configuration file or use the " +
 		"following command:")
 	p.Info("\n\tevcli set-config api.key <key>")
+	p.Info("\nThe key can also be read from a file with the api.key_file " +
+		"entry, from the output of a command such as a password manager " +
+		"with the api.key_command entry, or from the system keyring with " +
+		"the api.key_keyring entry.")
 	p.Info("\nAlternatively, you can set the EVENTLINE_API_KEY environment " +
 		"variable.")
-	os.Exit(1)
+	p.Exit(1)
+}
+
+// readAPIKey returns the api key provided by the environment or the
+// configuration, or an empty string if there is none.
+func (a *App) readAPIKey() (string, error) {
+	if key := os.Getenv("EVENTLINE_API_KEY"); key != "" {
+		p.Debug(1, "using api key from EVENTLINE_API_KEY environment variable")
+		return key, nil
+	}
+
+	if key := a.Config.API.Key; key != "" {
+		p.Debug(1, "using api key from configuration")
+		return key, nil
+	}
+
+	if filePath := a.Config.API.KeyFile; filePath != "" {
+		p.Debug(1, "using api key from %s", filePath)
+
+		key, err := ReadAPIKeyFile(filePath)
+		if err != nil {
+			return "", fmt.Errorf("cannot read api key file: %w", err)
+		}
+
+		return key, nil
+	}
+
+	if command := a.Config.API.KeyCommand; command != "" {
+		p.Debug(1, "using api key from command %q", command)
+
+		key, err := RunAPIKeyCommand(command)
+		if err != nil {
+			return "", fmt.Errorf("cannot obtain api key: %w", err)
+		}
+
+		return key, nil
+	}
+
+	if a.Config.API.KeyKeyring {
+		p.Debug(1, "using api key from keyring")
+
+		key, err := ReadKeyringAPIKey(a.Client.Endpoint)
+		if err != nil {
+			return "", fmt.Errorf("cannot read api key from keyring: %w", err)
+		}
+
+		return key, nil
+	}
+
+	return "", nil
 }
 
 func (a *App) IdentifyCurrentProject() {
@@ -96,7 +145,7 @@ func (a *App) identifyCurrentProject() (string, error) {
 
 		project, err := a.Client.FetchProjectByName(name)
 		if err != nil {
-			p.Fatal("cannot fetch project %q: %v", name, err)
+			return "", fmt.Errorf("cannot fetch project %q: %w", name, err)
 		}
 
 		return project.Id, nil
@@ -109,17 +158,22 @@ func (a *App) identifyCurrentProject() (string, error) {
 	if name := os.Getenv("EVENTLINE_PROJECT_NAME"); name != "" {
 		project, err := a.Client.FetchProjectByName(name)
 		if err != nil {
-			p.Fatal("cannot fetch project %q: %v", name, err)
+			return "", fmt.Errorf("cannot fetch project %q: %w", name, err)
 		}
 
 		return project.Id, nil
 	}
 
-	id, err := a.loadProjectDirectory(".")
+	dirPath, err := FindProjectDirectory(".")
 	if err != nil {
 		return "", err
-	} else if id != "" {
-		return id, nil
+	} else if dirPath != "" {
+		id, err := a.loadProjectDirectory(dirPath)
+		if err != nil {
+			return "", err
+		} else if id != "" {
+			return id, nil
+		}
 	}
 
 	return "", fmt.Errorf("cannot identify the current project")
@@ -138,48 +192,82 @@ func (a *App) loadProjectDirectory(dirPath string) (string, error) {
 	return projectFile.Id, nil
 }
 
-func (a *App) LookForLastBuild() {
-	lastCheck, err := a.loadLastBuildIdCheckDate()
-	if err != nil {
-		p.Error("cannot look for last build: %v", err)
+// ChildEnvironment returns the environment variables used to pass the
+// resolved configuration to child processes, i.e. plugins.
+func (a *App) ChildEnvironment() []string {
+	env := []string{
+		"EVCLI_DISABLE_UPDATE_CHECK=1",
+		"EVENTLINE_API_ENDPOINT=" + a.Client.Endpoint,
 	}
 
-	now := time.Now()
+	if !colorOutput {
+		env = append(env, "NO_COLOR=1")
+	}
 
-	if lastCheck != nil && now.Sub(*lastCheck) < 24*time.Hour {
-		return
+	if a.Client.APIKey != "" {
+		env = append(env, "EVENTLINE_API_KEY="+a.Client.APIKey)
 	}
 
-	lastBuildId, err := a.lookForLastBuild()
-	if err != nil {
-		p.Error("cannot find last build: %v", err)
-		return
+	if a.Client.ProjectId != "" {
+		env = append(env, "EVENTLINE_PROJECT_ID="+a.Client.ProjectId)
 	}
 
-	if err := a.updateLastBuildIdCheckDate(now); err != nil {
-		p.Error("cannot update last build check date: %v", err)
+	return env
+}
+
+func (a *App) ParseTimestamp(s string) (time.Time, error) {
+	return ParseTimestamp(s, time.Now(), a.Location)
+}
+
+func (a *App) TimestampOptionValue(name string) *time.Time {
+	if !p.IsOptionSet(name) {
+		return nil
 	}
 
-	if lastBuildId == nil {
-		p.Debug(1, "evcli is up-to-date")
-		return
+	t, err := a.ParseTimestamp(p.OptionValue(name))
+	if err != nil {
+		p.Fatal("invalid %s option: %v", name, err)
 	}
 
-	p.Info("evcli %v is now available: run \"evcli update\" to install it", lastBuildId)
+	return &t
 }
 
-func (a *App) lookForLastBuild() (*program.BuildId, error) {
+// lookForLastBuild returns the identifier of the last build if it is more
+// recent than the current one.
+func (a *App) lookForLastBuild(ignorePin bool) (*program.BuildId, error) {
 	p.Debug(1, "looking for the last build")
 
-	currentBuildId := a.currentBuildId()
-
-	lastBuildId, err := a.lastBuildId()
+	lastBuildId, err := a.lastBuildId(context.Background())
 	if err != nil {
 		return nil, fmt.Errorf("cannot retrieve last build id: %w", err)
-	} else if lastBuildId == nil {
+	}
+
+	return a.newerBuildId(lastBuildId, ignorePin)
+}
+
+// newerBuildId returns a build id if it is more recent than the current one,
+// taking the pinned version into account unless ignorePin is true.
+func (a *App) newerBuildId(lastBuildId *program.BuildId, ignorePin bool) (*program.BuildId, error) {
+	if lastBuildId == nil {
 		return nil, nil
 	}
 
+	currentBuildId := a.currentBuildId()
+
+	if !ignorePin {
+		pinnedBuildId, err := a.pinnedBuildId()
+		if err != nil {
+			return nil, err
+		}
+
+		if pinnedBuildId != nil &&
+			!lastBuildId.LowerThanOrEqualTo(*pinnedBuildId) {
+			p.Debug(1, "ignoring build %v since evcli is pinned to %v",
+				lastBuildId, pinnedBuildId)
+			lastBuildId = pinnedBuildId
+		}
+	}
+
 	if lastBuildId.LowerThanOrEqualTo(currentBuildId) {
 		return nil, nil
 	}
@@ -193,12 +281,33 @@ func (a *App) currentBuildId() program.BuildId {
 	return id
 }
 
-func (a *App) lastBuildId() (*program.BuildId, error) {
+func (a *App) pinnedBuildId() (*program.BuildId, error) {
+	s := a.Config.Misc.PinnedVersion
+	if s == "" {
+		return nil, nil
+	}
+
+	var id program.BuildId
+	if err := id.Parse(s); err != nil {
+		return nil, fmt.Errorf("invalid pinned version %q: %w", s, err)
+	}
+
+	return &id, nil
+}
+
+func (a *App) lastBuildId(ctx context.Context) (*program.BuildId, error) {
+	if uri := a.Config.Misc.UpdateURL; uri != "" {
+		index, err := FetchUpdateIndex(ctx, uri)
+		if err != nil {
+			return nil, err
+		}
+
+		return index.LastBuildId()
+	}
+
 	httpClient := a.HTTPClient
 	client := github.NewClient(httpClient)
 
-	ctx := context.Background()
-
 	release, _, err := client.Repositories.GetLatestRelease(ctx,
 		"exograd", "evcli")
 	if err != nil {
@@ -214,46 +323,3 @@ func (a *App) lastBuildId() (*program.BuildId, error) {
 
 	return &buildId, nil
 }
-
-func (a *App) loadLastBuildIdCheckDate() (*time.Time, error) {
-	filePath := a.lastBuildIdCheckPath()
-
-	data, err := ioutil.ReadFile(filePath)
-	if err != nil {
-		if errors.Is(err, os.ErrNotExist) {
-			return nil, nil
-		}
-
-		return nil, fmt.Errorf("cannot read %s: %w", filePath, err)
-	}
-
-	i, err := strconv.ParseInt(string(data), 10, 64)
-	if err != nil {
-		return nil, fmt.Errorf("%s: invalid data", filePath)
-	}
-
-	t := time.Unix(i, 0)
-
-	return &t, nil
-}
-
-func (a *App) updateLastBuildIdCheckDate(t time.Time) error {
-	filePath := a.lastBuildIdCheckPath()
-
-	data := []byte(strconv.FormatInt(t.Unix(), 10))
-
-	dirPath := filepath.Dir(filePath)
-	if err := os.MkdirAll(dirPath, 0700); err != nil {
-		return fmt.Errorf("cannot create directory %s: %w", dirPath, err)
-	}
-
-	if err := ioutil.WriteFile(filePath, data, 0600); err != nil {
-		return fmt.Errorf("cannot write %s: %w", filePath, err)
-	}
-
-	return nil
-}
-
-func (a *App) lastBuildIdCheckPath() string {
-	return path.Join(a.HomePath, ".cache", "evcli", "last-build-id-check")
-}
@@ -8,11 +8,12 @@ import (
 	"io/ioutil"
 	"net/http"
 	"net/url"
+	"os"
 	"strconv"
-	"strings"
 )
 
 type Client struct {
+	Endpoint  string
 	APIKey    string
 	ProjectId string
 
@@ -22,12 +23,21 @@ type Client struct {
 }
 
 func NewClient(config *Config) (*Client, error) {
-	baseURI, err := url.Parse(config.API.Endpoint)
+	// The endpoint can be overridden by the environment, e.g. for plugins
+	// executed by evcli itself.
+	endpoint := config.API.Endpoint
+	if s := os.Getenv("EVENTLINE_API_ENDPOINT"); s != "" {
+		endpoint = s
+	}
+
+	baseURI, err := url.Parse(endpoint)
 	if err != nil {
 		return nil, fmt.Errorf("invalid api endpoint: %w", err)
 	}
 
 	client := &Client{
+		Endpoint: endpoint,
+
 		baseURI: baseURI,
 	}
 
@@ -123,6 +133,19 @@ func (c *Client) FetchProjects() ([]*Project, error) {
 	return page.Elements, nil
 }
 
+func (c *Client) FetchProject(id string) (*Project, error) {
+	uri := NewURL("v0", "projects", "id", id)
+
+	var project Project
+
+	err := c.SendRequest("GET", uri, nil, &project)
+	if err != nil {
+		return nil, err
+	}
+
+	return &project, nil
+}
+
 func (c *Client) FetchProjectByName(name string) (*Project, error) {
 	uri := NewURL("v0", "projects", "name", name)
 
@@ -221,23 +244,69 @@ func (c *Client) ExecuteCommand(id string, input *CommandExecutionInput) (*Comma
 	return &execution, nil
 }
 
-func (c *Client) FetchPipelines() (Pipelines, error) {
+func (c *Client) FetchPipelines(filter *PipelineFilter) (Pipelines, error) {
+	var pipelines Pipelines
+
+	cursor := Cursor{
+		Size:  100,
+		Sort:  "event_time",
+		Order: OrderDesc,
+	}
+
+	if filter.Limit > 0 && filter.Limit < int(cursor.Size) {
+		cursor.Size = uint(filter.Limit)
+	}
+
+	for {
+		page, err := c.FetchPipelinePage(&cursor)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, pipeline := range page.Elements {
+			eventTime, err := pipeline.ParseEventTime()
+			if err != nil {
+				return nil, fmt.Errorf("invalid event time for pipeline "+
+					"%s: %w", pipeline.Id, err)
+			}
+
+			if filter.After != nil && eventTime.Before(*filter.After) {
+				// Pipelines are sorted by descending event time, there is
+				// no point in going further.
+				return pipelines, nil
+			}
+
+			if filter.Match(pipeline, eventTime) {
+				pipelines = append(pipelines, pipeline)
+
+				if filter.Limit > 0 && len(pipelines) >= filter.Limit {
+					return pipelines, nil
+				}
+			}
+		}
+
+		if page.Next == nil {
+			break
+		}
+
+		cursor = *page.Next
+	}
+
+	return pipelines, nil
+}
+
+func (c *Client) FetchPipelinePage(cursor *Cursor) (*PipelinePage, error) {
 	var page PipelinePage
 
 	uri := NewURL("v0", "pipelines")
-
-	query := url.Values{}
-	query.Add("size", "20")
-	query.Add("sort", "event_time")
-	query.Add("order", "desc")
-	uri.RawQuery = query.Encode()
+	uri.RawQuery = cursor.Query().Encode()
 
 	err := c.SendRequest("GET", uri, nil, &page)
 	if err != nil {
 		return nil, err
 	}
 
-	return page.Elements, nil
+	return &page, nil
 }
 
 func (c *Client) AbortPipeline(id string) error {
@@ -258,6 +327,73 @@ func (c *Client) RestartPipelineFromFailure(id string) error {
 	return c.SendRequest("POST", uri, nil, nil)
 }
 
+func (c *Client) FetchPipelineTasks(id string) (Tasks, error) {
+	var tasks Tasks
+
+	cursor := Cursor{Size: 20}
+
+	for {
+		var page TaskPage
+
+		uri := NewURL("v0", "pipelines", "id", id, "tasks")
+		uri.RawQuery = cursor.Query().Encode()
+
+		err := c.SendRequest("GET", uri, nil, &page)
+		if err != nil {
+			return nil, err
+		}
+
+		tasks = append(tasks, page.Elements...)
+
+		if page.Next == nil {
+			break
+		}
+
+		cursor = *page.Next
+	}
+
+	return tasks, nil
+}
+
+func (c *Client) FetchTask(id string) (*Task, error) {
+	uri := NewURL("v0", "tasks", "id", id)
+
+	var task Task
+
+	err := c.SendRequest("GET", uri, nil, &task)
+	if err != nil {
+		return nil, err
+	}
+
+	return &task, nil
+}
+
+// FetchTaskOutput returns the output of a task starting at a byte offset. If
+// step is nil, the output of all steps is returned.
+func (c *Client) FetchTaskOutput(id string, step *int, offset int) ([]byte, error) {
+	var uri *url.URL
+	if step == nil {
+		uri = NewURL("v0", "tasks", "id", id, "output")
+	} else {
+		uri = NewURL("v0", "tasks", "id", id, "steps", strconv.Itoa(*step),
+			"output")
+	}
+
+	query := url.Values{}
+	if offset > 0 {
+		query.Add("offset", strconv.Itoa(offset))
+	}
+	uri.RawQuery = query.Encode()
+
+	var output []byte
+
+	if err := c.SendRequest("GET", uri, nil, &output); err != nil {
+		return nil, err
+	}
+
+	return output, nil
+}
+
 func (c *Client) GetScratchpad(id string) (map[string]string, error) {
 	uri := NewURL("v0", "pipelines", "id", id, "scratchpad")
 
@@ -288,10 +424,10 @@ func (c *Client) GetScratchpadEntry(id, key string) (string, error) {
 	return string(value), nil
 }
 
-func (c *Client) SetScratchpadEntry(id, key, value string) error {
+func (c *Client) SetScratchpadEntry(id, key string, value io.Reader) error {
 	uri := NewURL("v0", "pipelines", "id", id, "scratchpad", "key", key)
 
-	return c.SendRequest("PUT", uri, strings.NewReader(value), nil)
+	return c.SendRequest("PUT", uri, value, nil)
 }
 
 func (c *Client) DeleteScratchpadEntry(id, key string) error {
@@ -313,6 +449,70 @@ func (c *Client) CreateEvent(newEvent *NewEvent) (Events, error) {
 	return events, nil
 }
 
+func (c *Client) FetchEventPage(cursor *Cursor) (*EventPage, error) {
+	var page EventPage
+
+	uri := NewURL("v0", "events")
+	uri.RawQuery = cursor.Query().Encode()
+
+	err := c.SendRequest("GET", uri, nil, &page)
+	if err != nil {
+		return nil, err
+	}
+
+	return &page, nil
+}
+
+func (c *Client) FetchEvents(filter *EventFilter) (Events, error) {
+	var events Events
+
+	cursor := Cursor{
+		Size:  100,
+		Sort:  "event_time",
+		Order: OrderDesc,
+	}
+
+	for {
+		page, err := c.FetchEventPage(&cursor)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, event := range page.Elements {
+			if filter.After != nil && event.EventTime.Before(*filter.After) {
+				// Events are sorted by descending event time, there is no
+				// point in going further.
+				return events, nil
+			}
+
+			if filter.Match(event) {
+				events = append(events, event)
+			}
+		}
+
+		if page.Next == nil {
+			break
+		}
+
+		cursor = *page.Next
+	}
+
+	return events, nil
+}
+
+func (c *Client) FetchEvent(id string) (*Event, error) {
+	uri := NewURL("v0", "events", "id", id)
+
+	var event Event
+
+	err := c.SendRequest("GET", uri, nil, &event)
+	if err != nil {
+		return nil, err
+	}
+
+	return &event, nil
+}
+
 func (c *Client) ReplayEvent(id string) (*Event, error) {
 	var event Event
 
@@ -10,20 +10,20 @@ import (
 )
 
 func addCommandCommands() {
-	var c *program.Command
+	var c *Command
 
 	// list-commands
-	c = p.AddCommand("list-commands", "list available commands",
+	c = registry.AddCommand("list-commands", "list available commands",
 		cmdListCommands)
 
 	// describe-command
-	c = p.AddCommand("describe-command", "print information about a command",
+	c = registry.AddCommand("describe-command", "print information about a command",
 		cmdDescribeCommand)
 
 	c.AddArgument("name", "the name of the command")
 
 	// execute-command
-	c = p.AddCommand("execute-command", "execute a command",
+	c = registry.AddCommand("execute-command", "execute a command",
 		cmdExecuteCommand)
 
 	c.AddArgument("name", "the name of the command")
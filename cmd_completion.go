@@ -0,0 +1,422 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/exograd/go-program"
+)
+
+// Results of API requests are cached for a short time since shells may run
+// the completion command several times for the same command line.
+const completionCacheTTL = 30 * time.Second
+
+const bashCompletionScript = `# bash completion for evcli
+
+_evcli() {
+    local line="${COMP_LINE:0:$COMP_POINT}"
+    local -a words
+    read -r -a words <<< "$line"
+    if [[ "$line" == *" " ]]; then
+        words+=("")
+    fi
+
+    local IFS=$'\n'
+    COMPREPLY=($("${words[0]}" __complete "${words[@]:1}" 2>/dev/null \
+        | cut -f 1))
+
+    if [[ ${#COMPREPLY[@]} -eq 1 && "${COMPREPLY[0]}" == *= ]]; then
+        compopt -o nospace
+    fi
+}
+
+complete -o default -F _evcli evcli
+`
+
+const zshCompletionScript = `#compdef evcli
+
+_evcli() {
+    local -a completions nospace
+    local line value
+
+    for line in "${(@f)$(${words[1]} __complete "${(@)words[2,CURRENT]}" 2>/dev/null)}"; do
+        [[ -n "$line" ]] || continue
+
+        value="${${line%%$'\t'*}//:/\\:}"
+        if [[ "$line" == *$'\t'* ]]; then
+            value="$value:${line#*$'\t'}"
+        fi
+
+        if [[ "$line" == *=(|$'\t'*) ]]; then
+            nospace+=("$value")
+        else
+            completions+=("$value")
+        fi
+    done
+
+    if (( ${#completions} + ${#nospace} == 0 )); then
+        _files
+        return
+    fi
+
+    _describe -t values 'values' completions
+    _describe -t values 'values' nospace -S ''
+}
+
+compdef _evcli evcli
+`
+
+const fishCompletionScript = `# fish completion for evcli
+
+function __evcli_complete
+    set -l words (commandline -opc)
+    set -l program $words[1]
+    set -e words[1]
+
+    set -l current (commandline -ct)
+    set -l completions ($program __complete $words $current 2>/dev/null)
+
+    if test (count $completions) -eq 0
+        __fish_complete_path $current
+        return
+    end
+
+    printf '%s\n' $completions
+end
+
+complete -c evcli -f -a '(__evcli_complete)'
+`
+
+func addCompletionCommands() {
+	var c *Command
+
+	// completion
+	c = registry.AddCommand("completion", "print a shell completion script",
+		cmdCompletion)
+
+	c.AddArgument("shell", "the shell (bash, zsh or fish)")
+}
+
+func cmdCompletion(p *program.Program) {
+	shell := p.ArgumentValue("shell")
+
+	var script string
+
+	switch shell {
+	case "bash":
+		script = bashCompletionScript
+	case "zsh":
+		script = zshCompletionScript
+	case "fish":
+		script = fishCompletionScript
+	default:
+		p.Fatal("unsupported shell %q", shell)
+	}
+
+	os.Stdout.WriteString(script)
+}
+
+func cmdComplete(words []string) {
+	// Plugins and aliases are added to a copy of the specification of the
+	// program since they are not commands.
+	spec := CompletionSpec{
+		Options:  registry.Spec.Options,
+		Commands: make(map[string]*CompletionCommand),
+	}
+
+	for name, command := range registry.Spec.Commands {
+		spec.Commands[name] = command
+	}
+
+	// Plugins are only registered as commands when they are used
+	if plugins, err := FindPlugins(); err == nil {
+		for _, plugin := range plugins {
+			if _, found := spec.Commands[plugin.Name]; !found {
+				spec.Commands[plugin.Name] = &CompletionCommand{
+					Name:        plugin.Name,
+					Description: "run the " + plugin.Path + " plugin",
+				}
+			}
+		}
+	}
+
+	for name := range app.Config.Aliases {
+		if _, found := spec.Commands[name]; !found {
+			spec.Commands[name] = &CompletionCommand{
+				Name: name,
+				Description: "alias for " +
+					strings.Join(app.Config.Aliases[name], " "),
+			}
+		}
+	}
+
+	completions, err := spec.Complete(words, completionSources())
+	if err != nil {
+		p.Fatal("cannot complete command line: %v", err)
+	}
+
+	for _, c := range completions {
+		if c.Description == "" {
+			fmt.Printf("%s\n", c.Value)
+		} else {
+			fmt.Printf("%s\t%s\n", c.Value, c.Description)
+		}
+	}
+}
+
+func completionSources() CompletionSources {
+	return CompletionSources{
+		Options: map[string]CompletionSource{
+			"project-name": completeProjectNames,
+			"status":       completePipelineStatuses,
+		},
+
+		Arguments: map[string]CompletionSource{
+			"abort-pipeline/pipeline-id":                completePipelineIds,
+			"delete-alias/name":                         completeAliasNames,
+			"delete-project/name":                       completeProjectNames,
+			"describe-command/name":                     completeCommandNames,
+			"execute-command/name":                      completeCommandNames,
+			"execute-command/parameter":                 completeCommandParameters,
+			"get-config/name":                           completeConfigEntryNames,
+			"help/command":                              completeHelpCommandNames,
+			"initialize-project/name":                   completeProjectNames,
+			"restart-pipeline/pipeline-id":              completePipelineIds,
+			"restart-pipeline-from-failure/pipeline-id": completePipelineIds,
+			"set-config/name":                           completeConfigEntryNames,
+			"show-task-output/pipeline-id":              completePipelineIds,
+		},
+	}
+}
+
+func completeAliasNames(ctx *CompletionContext) (Completions, error) {
+	var cs Completions
+	for name := range app.Config.Aliases {
+		cs = append(cs, Completion{Value: name})
+	}
+
+	sort.Slice(cs, func(i, j int) bool {
+		return cs[i].Value < cs[j].Value
+	})
+
+	return cs, nil
+}
+
+func completeConfigEntryNames(ctx *CompletionContext) (Completions, error) {
+	var cs Completions
+	for name := range ConfigEntries {
+		cs = append(cs, Completion{Value: name})
+	}
+
+	sort.Slice(cs, func(i, j int) bool {
+		return cs[i].Value < cs[j].Value
+	})
+
+	return cs, nil
+}
+
+func completeHelpCommandNames(ctx *CompletionContext) (Completions, error) {
+	spec := registry.Spec
+
+	var cs Completions
+	for _, name := range spec.CommandNames() {
+		cs = append(cs, Completion{
+			Value:       name,
+			Description: spec.Commands[name].Description,
+		})
+	}
+
+	return cs, nil
+}
+
+func completePipelineStatuses(ctx *CompletionContext) (Completions, error) {
+	var cs Completions
+	for _, status := range PipelineStatuses {
+		cs = append(cs, Completion{Value: status})
+	}
+
+	return cs, nil
+}
+
+func completeProjectNames(ctx *CompletionContext) (Completions, error) {
+	loadCompletionAPIKey()
+
+	return cachedCompletions("projects", func() (Completions, error) {
+		projects, err := app.Client.FetchProjects()
+		if err != nil {
+			return nil, err
+		}
+
+		var cs Completions
+		for _, project := range projects {
+			cs = append(cs, Completion{Value: project.Name})
+		}
+
+		return cs, nil
+	})
+}
+
+func completeCommandNames(ctx *CompletionContext) (Completions, error) {
+	if err := identifyCompletionProject(ctx); err != nil {
+		return nil, err
+	}
+
+	return cachedCompletions("commands", func() (Completions, error) {
+		commands, err := app.Client.FetchCommands()
+		if err != nil {
+			return nil, err
+		}
+
+		var cs Completions
+		for _, command := range commands {
+			cs = append(cs, Completion{
+				Value:       command.Spec.Name,
+				Description: command.Spec.Description,
+			})
+		}
+
+		return cs, nil
+	})
+}
+
+func completeCommandParameters(ctx *CompletionContext) (Completions, error) {
+	if err := identifyCompletionProject(ctx); err != nil {
+		return nil, err
+	}
+
+	name := ctx.Arguments["name"]
+
+	return cachedCompletions("parameters/"+name, func() (Completions, error) {
+		command, err := app.Client.FetchCommandByName(name)
+		if err != nil {
+			return nil, err
+		}
+
+		commandData := command.Spec.Data.(*CommandData)
+
+		var cs Completions
+		for _, parameter := range commandData.Parameters {
+			cs = append(cs, Completion{
+				Value:       parameter.Name + "=",
+				Description: parameter.Description,
+			})
+		}
+
+		return cs, nil
+	})
+}
+
+func completePipelineIds(ctx *CompletionContext) (Completions, error) {
+	if err := identifyCompletionProject(ctx); err != nil {
+		return nil, err
+	}
+
+	return cachedCompletions("pipelines", func() (Completions, error) {
+		pipelines, err := app.Client.FetchPipelines(&PipelineFilter{
+			Limit: 20,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		var cs Completions
+		for _, pipeline := range pipelines {
+			cs = append(cs, Completion{
+				Value: pipeline.Id,
+				Description: fmt.Sprintf("%s (%s)",
+					pipeline.Name, pipeline.Status),
+			})
+		}
+
+		return cs, nil
+	})
+}
+
+func identifyCompletionProject(ctx *CompletionContext) error {
+	loadCompletionAPIKey()
+
+	if id, found := ctx.Options["project-id"]; found {
+		app.projectIdOption = &id
+	}
+
+	if name, found := ctx.Options["project-name"]; found {
+		app.projectNameOption = &name
+	}
+
+	id, err := app.identifyCurrentProject()
+	if err != nil {
+		return err
+	}
+
+	app.Client.ProjectId = id
+
+	return nil
+}
+
+// loadCompletionAPIKey loads the API key unless it has already been loaded,
+// e.g. when completing a command line in the interactive shell.
+func loadCompletionAPIKey() {
+	if app.Client.APIKey == "" {
+		app.LoadAPIKey()
+	}
+}
+
+func cachedCompletions(kind string, fn func() (Completions, error)) (Completions, error) {
+	// Cache entries depend on the endpoint and the project so that
+	// completions never leak from one context to another.
+	key := strings.Join([]string{app.Client.Endpoint,
+		app.Client.ProjectId, kind}, "\x00")
+	hash := sha256.Sum256([]byte(key))
+
+	filePath := path.Join(app.HomePath, ".cache", "evcli", "completion",
+		hex.EncodeToString(hash[:16])+".json")
+
+	if info, err := os.Stat(filePath); err == nil &&
+		time.Since(info.ModTime()) < completionCacheTTL {
+		data, err := ioutil.ReadFile(filePath)
+		if err == nil {
+			var cs Completions
+			if err := json.Unmarshal(data, &cs); err == nil {
+				return cs, nil
+			}
+		}
+	}
+
+	cs, err := fn()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := writeCompletionCache(filePath, cs); err != nil {
+		p.Debug(1, "cannot write completion cache: %v", err)
+	}
+
+	return cs, nil
+}
+
+func writeCompletionCache(filePath string, cs Completions) error {
+	data, err := json.Marshal(cs)
+	if err != nil {
+		return fmt.Errorf("cannot encode completions: %w", err)
+	}
+
+	dirPath := filepath.Dir(filePath)
+	if err := os.MkdirAll(dirPath, 0700); err != nil {
+		return fmt.Errorf("cannot create directory %s: %w", dirPath, err)
+	}
+
+	if err := ioutil.WriteFile(filePath, data, 0600); err != nil {
+		return fmt.Errorf("cannot write %s: %w", filePath, err)
+	}
+
+	return nil
+}
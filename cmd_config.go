@@ -4,36 +4,62 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"regexp"
+	"sort"
+	"strings"
 
 	"github.com/exograd/go-program"
 )
 
 func addConfigCommands() {
-	var c *program.Command
+	var c *Command
 
 	// show-config
-	c = p.AddCommand("show-config", "print the configuration",
+	c = registry.AddCommand("show-config", "print the configuration",
 		cmdShowConfig)
 
 	c.AddFlag("e", "entries",
 		"show a list of entries instead of the entire configuration")
+	c.AddFlag("", "show-secrets", "do not mask sensitive values")
 
 	// get-config
-	c = p.AddCommand("get-config",
+	c = registry.AddCommand("get-config",
 		"extract a value from the configuration and print it",
 		cmdGetConfig)
 
 	c.AddArgument("name", "the name of the entry")
 
 	// set-config
-	c = p.AddCommand("set-config", "set a value in the configuration",
+	c = registry.AddCommand("set-config", "set a value in the configuration",
 		cmdSetConfig)
 
 	c.AddArgument("name", "the name of the entry")
 	c.AddArgument("value", "the value of the entry")
+
+	// list-aliases
+	c = registry.AddCommand("list-aliases", "list command aliases", cmdListAliases)
+
+	// set-alias
+	c = registry.AddCommand("set-alias", "create or replace a command alias",
+		cmdSetAlias)
+
+	c.AddArgument("name", "the name of the alias")
+	c.AddTrailingArgument("argument",
+		"an argument the alias expands to (use -- before options)")
+
+	// delete-alias
+	c = registry.AddCommand("delete-alias", "delete a command alias",
+		cmdDeleteAlias)
+
+	c.AddArgument("name", "the name of the alias")
 }
 
 func cmdShowConfig(p *program.Program) {
+	config := app.Config
+	if !p.IsOptionSet("show-secrets") {
+		config = config.Redacted()
+	}
+
 	if p.IsOptionSet("entries") {
 		var names []string
 		for _, e := range ConfigEntries {
@@ -42,7 +68,7 @@ func cmdShowConfig(p *program.Program) {
 
 		table := NewTable([]string{"name", "value"})
 		for _, name := range names {
-			value, err := app.Config.GetEntry(name)
+			value, err := config.GetEntry(name)
 			if err != nil {
 				p.Error("cannot read entry %q: %v", name, err)
 				continue
@@ -56,7 +82,7 @@ func cmdShowConfig(p *program.Program) {
 		encoder := json.NewEncoder(os.Stdout)
 		encoder.SetIndent("", "  ")
 
-		if err := encoder.Encode(app.Config); err != nil {
+		if err := encoder.Encode(config); err != nil {
 			p.Fatal("cannot encode configuration: %v", err)
 		}
 	}
@@ -85,3 +111,85 @@ func cmdSetConfig(p *program.Program) {
 		p.Fatal("%v", err)
 	}
 }
+
+func cmdListAliases(p *program.Program) {
+	var names []string
+	for name := range app.Config.Aliases {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	header := []string{"name", "command"}
+	table := NewTable(header)
+
+	for _, name := range names {
+		args := app.Config.Aliases[name]
+
+		words := make([]string, len(args))
+		for i, arg := range args {
+			if aliasUnquotedArgumentRE.MatchString(arg) {
+				words[i] = arg
+			} else {
+				words[i] = ShellQuote(arg)
+			}
+		}
+
+		row := []interface{}{name, strings.Join(words, " ")}
+		table.AddRow(row)
+	}
+
+	table.Write()
+}
+
+var aliasUnquotedArgumentRE = regexp.MustCompile(`^[A-Za-z0-9_@%+=:,./$-]+$`)
+
+func cmdSetAlias(p *program.Program) {
+	name := p.ArgumentValue("name")
+	args := p.TrailingArgumentValues("argument")
+
+	if len(args) > 0 && args[0] == "--" {
+		args = args[1:]
+	}
+
+	if name == "" || strings.HasPrefix(name, "-") ||
+		strings.ContainsAny(name, " \t\n") {
+		p.Fatal("invalid alias name %q", name)
+	}
+
+	if _, found := registry.Spec.Commands[name]; found {
+		p.Fatal("alias %q would be shadowed by a builtin command", name)
+	}
+
+	if len(args) == 0 {
+		p.Fatal("missing alias arguments")
+	}
+
+	if err := CheckAlias(args); err != nil {
+		p.Fatal("invalid alias: %v", err)
+	}
+
+	if app.Config.Aliases == nil {
+		app.Config.Aliases = make(map[string][]string)
+	}
+
+	app.Config.Aliases[name] = args
+
+	if err := app.Config.Write(); err != nil {
+		p.Fatal("%v", err)
+	}
+}
+
+func cmdDeleteAlias(p *program.Program) {
+	name := p.ArgumentValue("name")
+
+	if _, found := app.Config.Aliases[name]; !found {
+		p.Fatal("unknown alias %q", name)
+	}
+
+	delete(app.Config.Aliases, name)
+
+	if err := app.Config.Write(); err != nil {
+		p.Fatal("%v", err)
+	}
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/exograd/go-program"
+)
+
+func addDashboardCommands() {
+	var c *Command
+
+	// dashboard
+	c = registry.AddCommand("dashboard",
+		"display pipelines in a full screen terminal interface",
+		cmdDashboard)
+
+	addPipelineFilterOptions(c)
+	c.AddOption("l", "limit", "n", "50",
+		"the maximum number of pipelines to display")
+	c.AddOption("i", "interval", "duration", "5s",
+		"the interval between two refreshes")
+}
+
+func cmdDashboard(p *program.Program) {
+	app.IdentifyCurrentProject()
+
+	filter := pipelineFilterOptionValue(p)
+
+	limitString := p.OptionValue("limit")
+	limit, err := strconv.Atoi(limitString)
+	if err != nil || limit <= 0 {
+		p.Fatal("invalid limit %q", limitString)
+	}
+	filter.Limit = limit
+
+	intervalString := p.OptionValue("interval")
+	interval, err := ParseDuration(intervalString)
+	if err != nil || interval < time.Second {
+		p.Fatal("invalid interval %q", intervalString)
+	}
+
+	dashboard := NewDashboard(filter, interval)
+
+	if err := dashboard.Run(); err != nil {
+		p.Fatal("%v", err)
+	}
+}
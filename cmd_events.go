@@ -3,21 +3,24 @@ package main
 import (
 	"fmt"
 	"io/ioutil"
+	"net/http"
 	"os"
+	"sort"
+	"strconv"
 	"time"
 
 	"github.com/exograd/go-program"
 )
 
 func addEventCommands() {
-	var c *program.Command
+	var c *Command
 
 	// create-event
-	c = p.AddCommand("create-event", "create a new custom event",
+	c = registry.AddCommand("create-event", "create a new custom event",
 		cmdCreateEvent)
 
 	c.AddOption("t", "event-time", "timestamp", "",
-		"the date and time the event occurred (RFC 3339 format)")
+		"the date and time the event occurred")
 
 	c.AddArgument("connector", "the name of the connector")
 	c.AddArgument("event", "the name of the event")
@@ -25,24 +28,51 @@ func addEventCommands() {
 		"the JSON object representing event data (\"-\" to read stdin)")
 
 	// replay-event
-	c = p.AddCommand("replay-event", "replay an existing event",
+	c = registry.AddCommand("replay-event", "replay an existing event",
 		cmdReplayEvent)
 
 	c.AddArgument("event-id", "the identifier of the event")
+
+	// replay-events
+	c = registry.AddCommand("replay-events", "replay all events matching filters",
+		cmdReplayEvents)
+
+	c.AddOption("", "after", "timestamp", "",
+		"only replay events which occurred after this date")
+	c.AddOption("", "before", "timestamp", "",
+		"only replay events which occurred before this date")
+	c.AddOption("c", "connector", "name", "",
+		"only replay events of this connector")
+	c.AddOption("e", "event", "name", "",
+		"only replay events with this name")
+	c.AddFlag("f", "failed-pipelines",
+		"only replay events which triggered failed pipelines (during the "+
+			"last 7 days unless --after is set)")
+	c.AddOption("r", "rate", "n", "2",
+		"the maximum number of events replayed per second")
+
+	// relay-events
+	c = registry.AddCommand("relay-events",
+		"run an http server creating events from incoming requests",
+		cmdRelayEvents)
+
+	c.AddOption("l", "listen", "address", "localhost:8080",
+		"the address to listen on")
+	c.AddOption("c", "connector", "name", "generic",
+		"the name of the connector")
+	c.AddOption("e", "event", "name", "",
+		"the name of the event")
+
+	c.AddTrailingArgument("mapping",
+		"a field mapping of the form \"<name>=<expression>\"")
 }
 
 func cmdCreateEvent(p *program.Program) {
 	app.IdentifyCurrentProject()
 
 	var eventTime time.Time
-	if p.IsOptionSet("event-time") {
-		eventTimeString := p.OptionValue("event-time")
-
-		var err error
-		eventTime, err = time.Parse(time.RFC3339, eventTimeString)
-		if err != nil {
-			p.Fatal("invalid event time: %v", err)
-		}
+	if t := app.TimestampOptionValue("event-time"); t != nil {
+		eventTime = t.UTC()
 	} else {
 		eventTime = time.Now().UTC()
 	}
@@ -88,3 +118,188 @@ func cmdReplayEvent(p *program.Program) {
 
 	fmt.Printf("%s\n", event.Id)
 }
+
+func cmdReplayEvents(p *program.Program) {
+	app.IdentifyCurrentProject()
+
+	var filter EventFilter
+
+	filter.After = app.TimestampOptionValue("after")
+	filter.Before = app.TimestampOptionValue("before")
+	filter.Connector = p.OptionValue("connector")
+	filter.Name = p.OptionValue("event")
+
+	failedPipelines := p.IsOptionSet("failed-pipelines")
+
+	if filter.After == nil && filter.Before == nil &&
+		filter.Connector == "" && filter.Name == "" && !failedPipelines {
+		p.Fatal("at least one filter is required")
+	}
+
+	rateString := p.OptionValue("rate")
+	rate, err := strconv.ParseFloat(rateString, 64)
+	if err != nil || rate <= 0.0 {
+		p.Fatal("invalid rate %q", rateString)
+	}
+
+	var events Events
+
+	if failedPipelines {
+		// Pipelines and events are not bounded otherwise: we would go
+		// through the entire history of the project.
+		if filter.After == nil {
+			after := time.Now().AddDate(0, 0, -7)
+			filter.After = &after
+		}
+
+		events, err = fetchFailedPipelineEvents(&filter)
+	} else {
+		events, err = app.Client.FetchEvents(&filter)
+	}
+
+	if err != nil {
+		p.Fatal("cannot fetch events: %v", err)
+	}
+
+	if len(events) == 0 {
+		p.Info("no event found")
+		return
+	}
+
+	header := []string{"id", "connector", "name", "event time"}
+	table := NewTable(header)
+	for _, event := range events {
+		row := []interface{}{
+			event.Id,
+			event.Connector,
+			event.Name,
+			event.EventTime,
+		}
+
+		table.AddRow(row)
+	}
+
+	table.Write()
+
+	prompt := fmt.Sprintf("Do you want to replay %d events?", len(events))
+	if Confirm(prompt) == false {
+		p.Info("replay aborted")
+		return
+	}
+
+	ticker := time.NewTicker(time.Duration(float64(time.Second) / rate))
+	defer ticker.Stop()
+
+	nbFailures := 0
+
+	summary := NewTable([]string{"original event", "new event", "error"})
+	for i, event := range events {
+		if i > 0 {
+			<-ticker.C
+		}
+
+		newEvent, err := app.Client.ReplayEvent(event.Id)
+		if err != nil {
+			p.Error("cannot replay event %s: %v", event.Id, err)
+			summary.AddRow([]interface{}{event.Id, "", err.Error()})
+			nbFailures++
+			continue
+		}
+
+		p.Debug(1, "event %s replayed as %s", event.Id, newEvent.Id)
+		summary.AddRow([]interface{}{event.Id, newEvent.Id, ""})
+	}
+
+	summary.Write()
+
+	if nbFailures > 0 {
+		p.Fatal("%d/%d events could not be replayed", nbFailures, len(events))
+	}
+
+	p.Info("%d events replayed", len(events))
+}
+
+// fetchFailedPipelineEvents returns the events which triggered the failed
+// pipelines matching the event filter, fetching them one by one instead of
+// going through all events.
+func fetchFailedPipelineEvents(filter *EventFilter) (Events, error) {
+	pipelineFilter := PipelineFilter{
+		Statuses: []string{"failed"},
+		After:    filter.After,
+		Before:   filter.Before,
+	}
+
+	pipelines, err := app.Client.FetchPipelines(&pipelineFilter)
+	if err != nil {
+		return nil, fmt.Errorf("cannot fetch pipelines: %w", err)
+	}
+
+	var events Events
+	ids := make(map[string]struct{})
+
+	for _, pipeline := range pipelines {
+		if pipeline.EventId == "" {
+			continue
+		}
+
+		if _, found := ids[pipeline.EventId]; found {
+			continue
+		}
+		ids[pipeline.EventId] = struct{}{}
+
+		event, err := app.Client.FetchEvent(pipeline.EventId)
+		if err != nil {
+			return nil, fmt.Errorf("cannot fetch event %s: %w",
+				pipeline.EventId, err)
+		}
+
+		if filter.Match(event) {
+			events = append(events, event)
+		}
+	}
+
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].EventTime.After(events[j].EventTime)
+	})
+
+	return events, nil
+}
+
+func cmdRelayEvents(p *program.Program) {
+	app.IdentifyCurrentProject()
+
+	address := p.OptionValue("listen")
+
+	relay := EventRelay{
+		Connector: p.OptionValue("connector"),
+		EventName: p.OptionValue("event"),
+	}
+
+	if relay.EventName == "" {
+		p.Fatal("missing event name")
+	}
+
+	for _, s := range p.TrailingArgumentValues("mapping") {
+		mapping, err := ParseFieldMapping(s)
+		if err != nil {
+			p.Fatal("invalid mapping: %v", err)
+		}
+
+		relay.Mappings = append(relay.Mappings, mapping)
+	}
+
+	p.Info("relaying requests on %s to %s/%s events",
+		address, relay.Connector, relay.EventName)
+
+	server := http.Server{
+		Addr:    address,
+		Handler: &relay,
+
+		ReadHeaderTimeout: 10 * time.Second,
+		ReadTimeout:       30 * time.Second,
+	}
+
+	if err := server.ListenAndServe(); err != nil {
+		p.Fatal("cannot run http server: %v", err)
+	}
+}
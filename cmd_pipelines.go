@@ -1,40 +1,187 @@
 package main
 
 import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
 	"github.com/exograd/go-program"
 )
 
 func addPipelineCommands() {
-	var c *program.Command
+	var c *Command
 
 	// list-pipelines
-	c = p.AddCommand("list-pipelines", "list pipelines",
+	c = registry.AddCommand("list-pipelines", "list pipelines",
 		cmdListPipelines)
 
+	addPipelineFilterOptions(c)
+
+	c.AddOption("l", "limit", "n", "20",
+		"the maximum number of pipelines to list (0 for no limit)")
+	c.AddOption("c", "columns", "columns", "",
+		"a comma-separated list of additional columns to display "+
+			"(trigger, event, concurrency, creation-time, end-time)")
+
 	// abort-pipeline
-	c = p.AddCommand("abort-pipeline", "abort a pipeline",
+	c = registry.AddCommand("abort-pipeline", "abort a pipeline",
 		cmdAbortPipeline)
 
 	c.AddArgument("pipeline-id", "the pipeline to abort")
 
 	// restart-pipeline
-	c = p.AddCommand("restart-pipeline", "restart a pipeline",
+	c = registry.AddCommand("restart-pipeline", "restart a pipeline",
 		cmdRestartPipeline)
 
 	c.AddArgument("pipeline-id", "the pipeline to restart")
 
 	// restart-pipeline-from-failure
-	c = p.AddCommand("restart-pipeline-from-failure",
+	c = registry.AddCommand("restart-pipeline-from-failure",
 		"restart a pipeline from failed or aborted tasks",
 		cmdRestartPipelineFromFailure)
 
 	c.AddArgument("pipeline-id", "the pipeline to restart")
+
+	// abort-pipelines
+	c = registry.AddCommand("abort-pipelines",
+		"abort all active pipelines matching filters", cmdAbortPipelines)
+
+	addPipelineFilterOptions(c)
+	addBulkOptions(c)
+
+	// restart-pipelines
+	c = registry.AddCommand("restart-pipelines",
+		"restart all pipelines matching filters",
+		cmdRestartPipelines)
+
+	addPipelineFilterOptions(c)
+	addBulkOptions(c)
+
+	// restart-pipelines-from-failure
+	c = registry.AddCommand("restart-pipelines-from-failure",
+		"restart all pipelines matching filters from failed or aborted tasks",
+		cmdRestartPipelinesFromFailure)
+
+	addPipelineFilterOptions(c)
+	addBulkOptions(c)
+
+	// pipeline-stats
+	c = registry.AddCommand("pipeline-stats",
+		"print statistics about pipelines grouped by name",
+		cmdPipelineStats)
+
+	addPipelineFilterOptions(c)
+
+	c.AddOption("f", "format", "format", "table",
+		"the output format (table, json or csv)")
+	c.AddOption("", "slowest", "n", "3",
+		"the number of slowest runs to report for each pipeline")
+}
+
+func addBulkOptions(c *Command) {
+	c.AddOption("", "concurrency", "n", "4",
+		"the maximum number of pipelines processed at the same time")
+	c.AddOption("l", "limit", "n", "100",
+		"fail if more than this number of pipelines match filters")
+	c.AddFlag("", "all", "select pipelines regardless of their event "+
+		"time and without any limit")
+}
+
+func addPipelineFilterOptions(c *Command) {
+	c.AddOption("s", "status", "status", "",
+		"only select pipelines with this status (or a comma-separated "+
+			"list of statuses)")
+	c.AddOption("n", "name", "name", "",
+		"only select pipelines with this name")
+	c.AddOption("", "trigger-id", "id", "",
+		"only select pipelines created by this trigger")
+	c.AddOption("", "event-id", "id", "",
+		"only select pipelines created for this event")
+	c.AddOption("", "since", "timestamp", "",
+		"only select pipelines whose event occurred after this date")
+	c.AddOption("", "until", "timestamp", "",
+		"only select pipelines whose event occurred before this date")
+	c.AddOption("", "failed-since", "duration", "",
+		"only select failed pipelines whose event occurred during this "+
+			"duration or after this date")
+}
+
+func pipelineFilterOptionValue(p *program.Program) *PipelineFilter {
+	var filter PipelineFilter
+
+	if s := p.OptionValue("status"); s != "" {
+		for _, status := range strings.Split(s, ",") {
+			status = strings.TrimSpace(status)
+
+			if !isPipelineStatus(status) {
+				p.Fatal("invalid pipeline status %q (must be one of %s)",
+					status, strings.Join(PipelineStatuses, ", "))
+			}
+
+			filter.Statuses = append(filter.Statuses, status)
+		}
+	}
+
+	filter.Name = p.OptionValue("name")
+	filter.TriggerId = p.OptionValue("trigger-id")
+	filter.EventId = p.OptionValue("event-id")
+	filter.After = app.TimestampOptionValue("since")
+	filter.Before = app.TimestampOptionValue("until")
+
+	if p.IsOptionSet("failed-since") {
+		if p.IsOptionSet("status") || p.IsOptionSet("since") {
+			p.Fatal("--failed-since cannot be used with --status or --since")
+		}
+
+		s := p.OptionValue("failed-since")
+
+		var since time.Time
+		if d, err := ParseDuration(s); err == nil {
+			since = time.Now().Add(-d)
+		} else {
+			since, err = app.ParseTimestamp(s)
+			if err != nil {
+				p.Fatal("invalid failed-since option: %v", err)
+			}
+		}
+
+		filter.Statuses = []string{PipelineStatusFailed}
+		filter.After = &since
+	}
+
+	return &filter
 }
 
 func cmdListPipelines(p *program.Program) {
 	app.IdentifyCurrentProject()
 
-	pipelines, err := app.Client.FetchPipelines()
+	filter := pipelineFilterOptionValue(p)
+
+	limitString := p.OptionValue("limit")
+	limit, err := strconv.Atoi(limitString)
+	if err != nil || limit < 0 {
+		p.Fatal("invalid limit %q", limitString)
+	}
+	filter.Limit = limit
+
+	var columns []*PipelineColumn
+	if s := p.OptionValue("columns"); s != "" {
+		for _, name := range strings.Split(s, ",") {
+			column, err := FindExtraPipelineColumn(strings.TrimSpace(name))
+			if err != nil {
+				p.Fatal("%v", err)
+			}
+
+			columns = append(columns, column)
+		}
+	}
+
+	pipelines, err := app.Client.FetchPipelines(filter)
 	if err != nil {
 		p.Fatal("cannot fetch pipelines: %v", err)
 	}
@@ -48,6 +195,10 @@ func cmdListPipelines(p *program.Program) {
 		"status",
 	}
 
+	for _, column := range columns {
+		header = append(header, column.Label)
+	}
+
 	table := NewTable(header)
 	for _, pipeline := range pipelines {
 		row := []interface{}{
@@ -59,6 +210,10 @@ func cmdListPipelines(p *program.Program) {
 			pipeline.Status,
 		}
 
+		for _, column := range columns {
+			row = append(row, column.Value(pipeline))
+		}
+
 		table.AddRow(row)
 	}
 
@@ -100,3 +255,307 @@ func cmdRestartPipelineFromFailure(p *program.Program) {
 
 	p.Info("pipeline restarted")
 }
+
+func cmdAbortPipelines(p *program.Program) {
+	runBulkPipelineOperation(p, "abort", "aborted",
+		PipelineActiveStatuses, app.Client.AbortPipeline)
+}
+
+func cmdRestartPipelines(p *program.Program) {
+	runBulkPipelineOperation(p, "restart", "restarted",
+		nil, app.Client.RestartPipeline)
+}
+
+func cmdRestartPipelinesFromFailure(p *program.Program) {
+	runBulkPipelineOperation(p, "restart", "restarted",
+		[]string{PipelineStatusFailed, PipelineStatusAborted},
+		app.Client.RestartPipelineFromFailure)
+}
+
+// runBulkPipelineOperation applies an operation to all pipelines matching
+// the filter options. If statuses is not nil, it contains the statuses of
+// the pipelines the operation can be applied to: it is used as default
+// status filter, and other statuses are rejected.
+//
+// Unless --all is set, only pipelines whose event occurred during the last
+// seven days are selected if --since is not set, and the operation is
+// refused if more than --limit pipelines match.
+func runBulkPipelineOperation(p *program.Program, verb, pastVerb string, statuses []string, op func(string) error) {
+	app.IdentifyCurrentProject()
+
+	filter := pipelineFilterOptionValue(p)
+	if filter.IsEmpty() {
+		p.Fatal("at least one filter is required")
+	}
+
+	if statuses != nil {
+		for _, status := range filter.Statuses {
+			valid := false
+			for _, s := range statuses {
+				if status == s {
+					valid = true
+					break
+				}
+			}
+
+			if !valid {
+				p.Fatal("cannot %s %s pipelines (status must be %s)",
+					verb, status, strings.Join(statuses, " or "))
+			}
+		}
+
+		if len(filter.Statuses) == 0 {
+			filter.Statuses = statuses
+		}
+	}
+
+	limit := 0
+	if !p.IsOptionSet("all") {
+		if filter.After == nil {
+			after := time.Now().AddDate(0, 0, -7)
+			filter.After = &after
+		}
+
+		limitString := p.OptionValue("limit")
+		var err error
+		limit, err = strconv.Atoi(limitString)
+		if err != nil || limit < 1 {
+			p.Fatal("invalid limit %q", limitString)
+		}
+
+		// Fetch one more pipeline than the limit to detect that it has been
+		// exceeded.
+		filter.Limit = limit + 1
+	}
+
+	concurrencyString := p.OptionValue("concurrency")
+	concurrency, err := strconv.Atoi(concurrencyString)
+	if err != nil || concurrency < 1 {
+		p.Fatal("invalid concurrency %q", concurrencyString)
+	}
+
+	pipelines, err := app.Client.FetchPipelines(filter)
+	if err != nil {
+		p.Fatal("cannot fetch pipelines: %v", err)
+	}
+
+	if len(pipelines) == 0 {
+		p.Info("no pipeline found")
+		return
+	}
+
+	if limit > 0 && len(pipelines) > limit {
+		p.Fatal("more than %d pipelines match filters (use --limit or "+
+			"--all to process more pipelines)", limit)
+	}
+
+	header := []string{"id", "name", "event time", "status"}
+	table := NewTable(header)
+	for _, pipeline := range pipelines {
+		row := []interface{}{
+			pipeline.Id,
+			pipeline.Name,
+			pipeline.EventTime,
+			pipeline.Status,
+		}
+
+		table.AddRow(row)
+	}
+
+	table.Write()
+
+	prompt := fmt.Sprintf("Do you want to %s %d pipelines?",
+		verb, len(pipelines))
+	if Confirm(prompt) == false {
+		p.Info("operation aborted")
+		return
+	}
+
+	errs := make([]error, len(pipelines))
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, concurrency)
+
+	for i, pipeline := range pipelines {
+		wg.Add(1)
+		sem <- struct{}{}
+
+		go func(i int, pipeline *Pipeline) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			p.Debug(1, "processing pipeline %s", pipeline.Id)
+
+			errs[i] = op(pipeline.Id)
+		}(i, pipeline)
+	}
+
+	wg.Wait()
+
+	nbFailures := 0
+
+	summary := NewTable([]string{"id", "name", "result"})
+	for i, pipeline := range pipelines {
+		result := pastVerb
+		if err := errs[i]; err != nil {
+			result = "error: " + err.Error()
+			nbFailures++
+		}
+
+		summary.AddRow([]interface{}{pipeline.Id, pipeline.Name, result})
+	}
+
+	summary.Write()
+
+	if nbFailures > 0 {
+		p.Fatal("%d/%d pipelines could not be %s",
+			nbFailures, len(pipelines), pastVerb)
+	}
+
+	p.Info("%d pipelines %s", len(pipelines), pastVerb)
+}
+
+func cmdPipelineStats(p *program.Program) {
+	app.IdentifyCurrentProject()
+
+	filter := pipelineFilterOptionValue(p)
+	if filter.After == nil {
+		after := time.Now().AddDate(0, 0, -7)
+		filter.After = &after
+	}
+
+	format := p.OptionValue("format")
+	switch format {
+	case "table", "json", "csv":
+	default:
+		p.Fatal("invalid format %q", format)
+	}
+
+	nbSlowestString := p.OptionValue("slowest")
+	nbSlowest, err := strconv.Atoi(nbSlowestString)
+	if err != nil || nbSlowest < 0 {
+		p.Fatal("invalid number of slowest runs %q", nbSlowestString)
+	}
+
+	pipelines, err := app.Client.FetchPipelines(filter)
+	if err != nil {
+		p.Fatal("cannot fetch pipelines: %v", err)
+	}
+
+	statsList := ComputePipelineStats(pipelines, nbSlowest)
+
+	switch format {
+	case "table":
+		writePipelineStatsTable(statsList)
+
+	case "json":
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+
+		if err := encoder.Encode(statsList); err != nil {
+			p.Fatal("cannot encode statistics: %v", err)
+		}
+
+	case "csv":
+		if err := writePipelineStatsCSV(statsList); err != nil {
+			p.Fatal("cannot write statistics: %v", err)
+		}
+	}
+}
+
+func writePipelineStatsTable(statsList PipelineStatsList) {
+	header := []string{"name", "count"}
+	header = append(header, PipelineStatuses...)
+	header = append(header, "success rate", "p50", "p90", "p99")
+
+	table := NewTable(header)
+	for _, stats := range statsList {
+		row := []interface{}{stats.Name, stats.Count}
+		for _, status := range PipelineStatuses {
+			row = append(row, stats.StatusCounts[status])
+		}
+
+		var successRate string
+		if stats.SuccessRate != nil {
+			successRate = fmt.Sprintf("%.1f%%", *stats.SuccessRate*100.0)
+		}
+
+		row = append(row, successRate, stats.P50, stats.P90, stats.P99)
+
+		table.AddRow(row)
+	}
+
+	table.Write()
+
+	slowestTable := NewTable([]string{"name", "id", "event time",
+		"duration", "status"})
+	for _, stats := range statsList {
+		for _, pipeline := range stats.SlowestRuns {
+			row := []interface{}{
+				pipeline.Name,
+				pipeline.Id,
+				pipeline.EventTime,
+				pipeline.Duration(),
+				pipeline.Status,
+			}
+
+			slowestTable.AddRow(row)
+		}
+	}
+
+	if len(slowestTable.Rows) > 0 {
+		fmt.Println("")
+		slowestTable.Write()
+	}
+}
+
+func writePipelineStatsCSV(statsList PipelineStatsList) error {
+	w := csv.NewWriter(os.Stdout)
+
+	header := []string{"name", "count"}
+	header = append(header, PipelineStatuses...)
+	header = append(header, "success_rate", "p50", "p90", "p99",
+		"slowest_runs")
+
+	if err := w.Write(header); err != nil {
+		return err
+	}
+
+	seconds := func(d *time.Duration) string {
+		if d == nil {
+			return ""
+		}
+
+		return strconv.FormatFloat(d.Seconds(), 'f', 3, 64)
+	}
+
+	for _, stats := range statsList {
+		record := []string{stats.Name, strconv.Itoa(stats.Count)}
+		for _, status := range PipelineStatuses {
+			record = append(record, strconv.Itoa(stats.StatusCounts[status]))
+		}
+
+		var successRate string
+		if stats.SuccessRate != nil {
+			successRate = strconv.FormatFloat(*stats.SuccessRate, 'f', 4, 64)
+		}
+
+		slowestIds := make([]string, len(stats.SlowestRuns))
+		for i, pipeline := range stats.SlowestRuns {
+			slowestIds[i] = pipeline.Id
+		}
+
+		record = append(record, successRate,
+			seconds(stats.P50), seconds(stats.P90), seconds(stats.P99),
+			strings.Join(slowestIds, " "))
+
+		if err := w.Write(record); err != nil {
+			return err
+		}
+	}
+
+	w.Flush()
+	return w.Error()
+}
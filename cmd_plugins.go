@@ -0,0 +1,122 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+
+	"github.com/exograd/go-program"
+)
+
+func addPluginCommands() {
+	// list-plugins
+	registry.AddCommand("list-plugins", "list available plugins", cmdListPlugins)
+}
+
+// addPluginCommand registers a command for the plugin used in the command
+// line if there is one and returns it. It must be called before parsing the
+// command line.
+func addPluginCommand() *Plugin {
+	spec := registry.Spec
+
+	args := os.Args[1:]
+
+	i := spec.CommandNameIndex(args)
+	if i == -1 {
+		return nil
+	}
+
+	name := args[i]
+
+	if _, found := spec.Commands[name]; found {
+		return nil
+	}
+
+	plugin, err := FindPlugin(name)
+	if err != nil {
+		// Let go-program report the unknown command
+		return nil
+	}
+
+	c := registry.AddCommand(name, "run the "+plugin.Path+" plugin",
+		func(p *program.Program) {
+			cmdPlugin(p, plugin)
+		})
+
+	c.AddTrailingArgument("argument", "an argument passed to the plugin")
+
+	// Plugin arguments must not be interpreted as evcli options
+	newArgs := append([]string{}, os.Args[:i+2]...)
+	newArgs = append(newArgs, "--")
+	newArgs = append(newArgs, os.Args[i+2:]...)
+
+	os.Args = newArgs
+
+	return plugin
+}
+
+func cmdPlugin(p *program.Program, plugin *Plugin) {
+	args := p.TrailingArgumentValues("argument")
+	if len(args) > 0 && args[0] == "--" {
+		args = args[1:]
+	}
+
+	// Plugins do not necessarily use the api or work on a project: the api
+	// key and the current project are passed to the plugin if they are
+	// available.
+	if app.Client.APIKey == "" {
+		if key, err := app.readAPIKey(); err == nil {
+			app.Client.APIKey = key
+		} else {
+			p.Debug(1, "%v", err)
+		}
+	}
+
+	if id, err := app.identifyCurrentProject(); err == nil {
+		app.Client.ProjectId = id
+	} else {
+		p.Debug(1, "%v", err)
+	}
+
+	p.Debug(1, "running plugin %s", plugin.Path)
+
+	cmd := exec.Command(plugin.Path, args...)
+	cmd.Env = append(os.Environ(), app.ChildEnvironment()...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	if err := cmd.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			p.Exit(exitErr.ExitCode())
+		}
+
+		p.Fatal("cannot execute %s: %v", plugin.Path, err)
+	}
+}
+
+func cmdListPlugins(p *program.Program) {
+	plugins, err := FindPlugins()
+	if err != nil {
+		p.Fatal("cannot find plugins: %v", err)
+	}
+
+	spec := registry.Spec
+
+	header := []string{"name", "path"}
+	table := NewTable(header)
+
+	for _, plugin := range plugins {
+		if _, found := spec.Commands[plugin.Name]; found {
+			p.Debug(1, "ignoring plugin %s shadowed by a builtin command",
+				plugin.Path)
+			continue
+		}
+
+		row := []interface{}{plugin.Name, plugin.Path}
+		table.AddRow(row)
+	}
+
+	table.Write()
+}
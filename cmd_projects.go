@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
 	"sort"
 	"strconv"
 
@@ -13,14 +14,14 @@ import (
 )
 
 func addProjectCommands() {
-	var c *program.Command
+	var c *Command
 
 	// list-projects
-	c = p.AddCommand("list-projects", "list projects",
+	c = registry.AddCommand("list-projects", "list projects",
 		cmdListProjects)
 
 	// initialize-project
-	c = p.AddCommand("initialize-project",
+	c = registry.AddCommand("initialize-project",
 		"initialize a directory for an existing project",
 		cmdInitializeProject)
 
@@ -28,32 +29,32 @@ func addProjectCommands() {
 	c.AddArgument("path", "the directory which will contain project data")
 
 	// create-project
-	c = p.AddCommand("create-project", "create a new project",
+	c = registry.AddCommand("create-project", "create a new project",
 		cmdCreateProject)
 
 	c.AddArgument("name", "the name of the project")
 	c.AddArgument("path", "the directory which will contain project data")
 
 	// delete-project
-	c = p.AddCommand("delete-project", "delete a project",
+	c = registry.AddCommand("delete-project", "delete a project",
 		cmdDeleteProject)
 
 	c.AddArgument("name", "the name of the project")
 
 	// deploy-project
-	c = p.AddCommand("deploy-project", "deploy resources for a project",
+	c = registry.AddCommand("deploy-project", "deploy resources for a project",
 		cmdDeployProject)
 
-	c.AddOption("d", "directory", "path", ".",
-		"the directory containing project data")
+	c.AddOption("d", "directory", "path", "",
+		"the directory containing project data (default: the project root)")
 	c.AddFlag("n", "dry-run", "validate resources but do not deploy them")
 
 	// list-project-files
-	c = p.AddCommand("list-project-files",
+	c = registry.AddCommand("list-project-files",
 		"list resource files in a project directory", cmdListProjectFiles)
 
-	c.AddOption("d", "directory", "path", ".",
-		"the directory containing project data")
+	c.AddOption("d", "directory", "path", "",
+		"the directory containing project data (default: the project root)")
 }
 
 func cmdListProjects(p *program.Program) {
@@ -89,7 +90,7 @@ func cmdInitializeProject(p *program.Program) {
 			p.Error("unknown project")
 			p.Info("\nYou can use the create-project command to create a " +
 				"new project and initialize its directory.")
-			os.Exit(1)
+			p.Exit(1)
 		} else {
 			p.Fatal("cannot fetch project %q: %v", name, err)
 		}
@@ -164,7 +165,7 @@ func cmdDeleteProject(p *program.Program) {
 }
 
 func cmdDeployProject(p *program.Program) {
-	dirPath := p.OptionValue("directory")
+	dirPath := projectDirectoryOptionValue(p)
 	dryRun := p.IsOptionSet("dry-run")
 
 	var projectFile ProjectFile
@@ -263,8 +264,32 @@ func formatInvalidRequestBodyError(err InvalidRequestBodyError, resourceSet *Res
 	return buf.String()
 }
 
+func projectDirectoryOptionValue(p *program.Program) string {
+	if p.IsOptionSet("directory") {
+		return p.OptionValue("directory")
+	}
+
+	dirPath, err := FindProjectDirectory(".")
+	if err != nil {
+		p.Fatal("cannot find project directory: %v", err)
+	} else if dirPath == "" {
+		return "."
+	}
+
+	// Relative paths are easier to read in messages
+	if cwd, err := os.Getwd(); err == nil {
+		if relPath, err := filepath.Rel(cwd, dirPath); err == nil {
+			dirPath = relPath
+		}
+	}
+
+	p.Debug(1, "using project directory %s", dirPath)
+
+	return dirPath
+}
+
 func cmdListProjectFiles(p *program.Program) {
-	dirPath := p.OptionValue("directory")
+	dirPath := projectDirectoryOptionValue(p)
 
 	var ignoreSet IgnoreSet
 	if err := ignoreSet.LoadDirectoryIfExists(dirPath); err != nil {
@@ -278,10 +303,12 @@ func cmdListProjectFiles(p *program.Program) {
 
 	sort.Strings(filePaths)
 
-	dirPathLen := len(dirPath)
-
 	for _, filePath := range filePaths {
-		relFilePath := filePath[dirPathLen+1:]
+		relFilePath, err := filepath.Rel(dirPath, filePath)
+		if err != nil {
+			p.Fatal("cannot compute relative path for %s: %v", filePath, err)
+		}
+
 		fmt.Printf("%s\n", relFilePath)
 	}
 }
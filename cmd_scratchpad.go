@@ -1,17 +1,24 @@
 package main
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"os"
+	"os/exec"
 	"sort"
+	"strings"
+	"time"
 
 	"github.com/exograd/go-program"
 )
 
 func addScratchpadCommands() {
-	var c *program.Command
+	var c *Command
 
-	addCommonOptions := func(c *program.Command) {
+	addCommonOptions := func(c *Command) {
 		c.AddOption("", "pipeline-id", "id", "",
 			"the identifier of the pipeline")
 	}
@@ -31,7 +38,7 @@ func addScratchpadCommands() {
 					"pipeline id. You can either use the --pipeline-id " +
 					"option or set the EVENTLINE_PIPELINE_ID environment " +
 					"variable.")
-				os.Exit(1)
+				p.Exit(1)
 			}
 
 			f(p, id)
@@ -39,20 +46,20 @@ func addScratchpadCommands() {
 	}
 
 	// show-scratchpad
-	c = p.AddCommand("show-scratchpad", "list scratchpad entries",
+	c = registry.AddCommand("show-scratchpad", "list scratchpad entries",
 		scratchpadCmd(cmdShowScratchpad))
 
 	addCommonOptions(c)
 
 	// clear-scratchpad
-	c = p.AddCommand("clear-scratchpad",
+	c = registry.AddCommand("clear-scratchpad",
 		"delete all entries in the scratchpad",
 		scratchpadCmd(cmdClearScratchpad))
 
 	addCommonOptions(c)
 
 	// get-scratchpad-entry
-	c = p.AddCommand("get-scratchpad-entry",
+	c = registry.AddCommand("get-scratchpad-entry",
 		"get the value of a scratchpad entry",
 		scratchpadCmd(cmdGetScratchpadEntry))
 
@@ -61,22 +68,118 @@ func addScratchpadCommands() {
 	c.AddArgument("key", "the key of the entry")
 
 	// set-scratchpad-entry
-	c = p.AddCommand("set-scratchpad-entry",
-		"set the value of a scratchpad entry",
+	c = registry.AddCommand("set-scratchpad-entry",
+		"set the value of a scratchpad entry (conditional writes are not "+
+			"atomic)",
 		scratchpadCmd(cmdSetScratchpadEntry))
 
 	addCommonOptions(c)
 
+	c.AddFlag("", "if-absent", "fail if the entry already exists (not "+
+		"atomic: the entry is checked before and after being written)")
+	c.AddOption("", "if-equals", "value", "",
+		"fail if the current value of the entry is different (not atomic: "+
+			"the entry is checked before and after being written)")
+
+	c.AddArgument("key", "the key of the entry")
+	c.AddArgument("value", "the value of the entry (\"-\" to read stdin, "+
+		"\"@<path>\" to read a file)")
+
+	// wait-scratchpad-entry
+	c = registry.AddCommand("wait-scratchpad-entry",
+		"wait until a scratchpad entry exists",
+		scratchpadCmd(cmdWaitScratchpadEntry))
+
+	addCommonOptions(c)
+
+	c.AddOption("", "value", "value", "",
+		"wait until the entry has this value")
+	c.AddOption("t", "timeout", "duration", "5m",
+		"the maximum duration to wait for")
+	c.AddOption("i", "interval", "duration", "2s",
+		"the interval between two scratchpad requests")
+
 	c.AddArgument("key", "the key of the entry")
-	c.AddArgument("value", "the value of the entry")
 
 	// delete-scratchpad-entry
-	c = p.AddCommand("delete-scratchpad-entry", "delete a scratchpad entry",
+	c = registry.AddCommand("delete-scratchpad-entry", "delete a scratchpad entry",
 		scratchpadCmd(cmdDeleteScratchpadEntry))
 
 	addCommonOptions(c)
 
 	c.AddArgument("key", "the key of the entry")
+
+	// export-scratchpad
+	c = registry.AddCommand("export-scratchpad",
+		"write all scratchpad entries to a file",
+		scratchpadCmd(cmdExportScratchpad))
+
+	addCommonOptions(c)
+
+	c.AddOption("f", "format", "format", "",
+		"the output format (json, yaml or dotenv)")
+	c.AddOption("o", "output", "path", "",
+		"the file to write entries to (default: standard output)")
+
+	// import-scratchpad
+	c = registry.AddCommand("import-scratchpad",
+		"load scratchpad entries from a file",
+		scratchpadCmd(cmdImportScratchpad))
+
+	addCommonOptions(c)
+
+	c.AddOption("f", "format", "format", "",
+		"the input format (json, yaml or dotenv)")
+	c.AddFlag("", "replace", "delete all existing entries before importing")
+	c.AddFlag("", "merge", "overwrite existing entries with imported ones "+
+		"and keep the others")
+
+	c.AddArgument("path", "the file to read entries from (\"-\" to read stdin)")
+
+	// scratchpad-env
+	c = registry.AddCommand("scratchpad-env",
+		"print scratchpad entries as shell variable exports",
+		scratchpadCmd(cmdScratchpadEnv))
+
+	addCommonOptions(c)
+
+	c.AddOption("", "prefix", "string", "",
+		"a prefix added to all variable names")
+
+	// scratchpad-exec
+	c = registry.AddCommand("scratchpad-exec",
+		"execute a command with scratchpad entries in its environment",
+		scratchpadCmd(cmdScratchpadExec))
+
+	addCommonOptions(c)
+
+	c.AddOption("", "prefix", "string", "",
+		"a prefix added to all variable names")
+
+	c.AddTrailingArgument("command",
+		"the command to execute and its arguments, after \"--\"")
+}
+
+func scratchpadFormatOptionValue(p *program.Program, filePath string) string {
+	format := p.OptionValue("format")
+
+	if format == "" {
+		if filePath == "" || filePath == "-" {
+			return "json"
+		}
+
+		return ScratchpadFormatFromPath(filePath)
+	}
+
+	for _, f := range ScratchpadFormats {
+		if format == f {
+			return format
+		}
+	}
+
+	p.Fatal("invalid format %q (must be one of %s)",
+		format, strings.Join(ScratchpadFormats, ", "))
+	return ""
 }
 
 func cmdShowScratchpad(p *program.Program, id string) {
@@ -122,15 +225,142 @@ func cmdGetScratchpadEntry(p *program.Program, id string) {
 
 func cmdSetScratchpadEntry(p *program.Program, id string) {
 	key := p.ArgumentValue("key")
-	value := p.ArgumentValue("value")
+	valueString := p.ArgumentValue("value")
+
+	ifAbsent := p.IsOptionSet("if-absent")
+	ifEquals := p.IsOptionSet("if-equals")
+
+	if ifAbsent && ifEquals {
+		p.Fatal("--if-absent and --if-equals are mutually exclusive")
+	}
+
+	// The API does not provide any atomic operation on scratchpads, so
+	// conditions are checked before setting the entry, and the entry is
+	// read back afterwards to detect concurrent writers. This narrows the
+	// race window but does not close it: tasks using conditional writes for
+	// locks or barriers must account for this.
+	if ifAbsent || ifEquals {
+		entries, err := app.Client.GetScratchpad(id)
+		if err != nil {
+			p.Fatal("cannot fetch scratchpad: %v", err)
+		}
+
+		currentValue, found := entries[key]
+
+		if ifAbsent && found {
+			p.Fatal("scratchpad entry %q already exists", key)
+		}
+
+		if ifEquals {
+			expectedValue := p.OptionValue("if-equals")
+
+			if !found {
+				p.Fatal("scratchpad entry %q does not exist", key)
+			} else if currentValue != expectedValue {
+				p.Fatal("scratchpad entry %q has value %q, expected %q",
+					key, currentValue, expectedValue)
+			}
+		}
+	}
+
+	var value io.Reader
+
+	if valueString == "-" {
+		value = os.Stdin
+	} else if strings.HasPrefix(valueString, "@") {
+		filePath := valueString[1:]
+
+		file, err := os.Open(filePath)
+		if err != nil {
+			p.Fatal("cannot open %s: %v", filePath, err)
+		}
+		defer file.Close()
+
+		value = file
+	} else {
+		value = strings.NewReader(valueString)
+	}
+
+	// Conditional writes need the value to compare it with the entry once
+	// written.
+	var data []byte
+	if ifAbsent || ifEquals {
+		var err error
+		data, err = ioutil.ReadAll(value)
+		if err != nil {
+			p.Fatal("cannot read value: %v", err)
+		}
+
+		value = bytes.NewReader(data)
+	}
 
 	if err := app.Client.SetScratchpadEntry(id, key, value); err != nil {
 		p.Fatal("cannot set scratchpad entry: %v", err)
 	}
 
+	if ifAbsent || ifEquals {
+		currentValue, err := app.Client.GetScratchpadEntry(id, key)
+		if err != nil {
+			p.Fatal("cannot fetch scratchpad entry: %v", err)
+		}
+
+		if currentValue != string(data) {
+			p.Fatal("scratchpad entry %q was modified by another writer",
+				key)
+		}
+	}
+
 	p.Info("scratchpad entry %q set", key)
 }
 
+func cmdWaitScratchpadEntry(p *program.Program, id string) {
+	key := p.ArgumentValue("key")
+
+	var expectedValue *string
+	if p.IsOptionSet("value") {
+		value := p.OptionValue("value")
+		expectedValue = &value
+	}
+
+	timeoutString := p.OptionValue("timeout")
+	timeout, err := ParseDuration(timeoutString)
+	if err != nil {
+		p.Fatal("invalid timeout %q", timeoutString)
+	}
+
+	intervalString := p.OptionValue("interval")
+	interval, err := ParseDuration(intervalString)
+	if err != nil || interval <= 0 {
+		p.Fatal("invalid interval %q", intervalString)
+	}
+
+	deadline := time.Now().Add(timeout)
+
+	for {
+		entries, err := app.Client.GetScratchpad(id)
+		if err != nil {
+			p.Fatal("cannot fetch scratchpad: %v", err)
+		}
+
+		value, found := entries[key]
+		if found && (expectedValue == nil || value == *expectedValue) {
+			break
+		}
+
+		delay := time.Until(deadline)
+		if delay <= 0 {
+			p.Fatal("timeout while waiting for scratchpad entry %q", key)
+		} else if delay > interval {
+			delay = interval
+		}
+
+		p.Debug(1, "waiting for scratchpad entry %q", key)
+		time.Sleep(delay)
+	}
+
+	p.Info("scratchpad entry %q found", key)
+}
+
 func cmdDeleteScratchpadEntry(p *program.Program, id string) {
 	key := p.ArgumentValue("key")
 
@@ -140,3 +370,185 @@ func cmdDeleteScratchpadEntry(p *program.Program, id string) {
 
 	p.Info("scratchpad entry %q deleted", key)
 }
+
+func cmdExportScratchpad(p *program.Program, id string) {
+	filePath := p.OptionValue("output")
+	format := scratchpadFormatOptionValue(p, filePath)
+
+	entries, err := app.Client.GetScratchpad(id)
+	if err != nil {
+		p.Fatal("cannot fetch scratchpad: %v", err)
+	}
+
+	data, err := EncodeScratchpad(entries, format)
+	if err != nil {
+		p.Fatal("cannot encode scratchpad: %v", err)
+	}
+
+	if filePath == "" || filePath == "-" {
+		os.Stdout.Write(data)
+		return
+	}
+
+	if err := ioutil.WriteFile(filePath, data, 0600); err != nil {
+		p.Fatal("cannot write %s: %v", filePath, err)
+	}
+
+	p.Info("%d scratchpad entries exported to %s", len(entries), filePath)
+}
+
+func cmdImportScratchpad(p *program.Program, id string) {
+	filePath := p.ArgumentValue("path")
+	format := scratchpadFormatOptionValue(p, filePath)
+
+	replace := p.IsOptionSet("replace")
+	merge := p.IsOptionSet("merge")
+	if replace && merge {
+		p.Fatal("--replace and --merge are mutually exclusive")
+	}
+
+	var data []byte
+	var err error
+
+	if filePath == "-" {
+		data, err = ioutil.ReadAll(os.Stdin)
+		if err != nil {
+			p.Fatal("cannot read stdin: %v", err)
+		}
+	} else {
+		data, err = ioutil.ReadFile(filePath)
+		if err != nil {
+			p.Fatal("cannot read %s: %v", filePath, err)
+		}
+	}
+
+	entries, err := DecodeScratchpad(data, format)
+	if err != nil {
+		p.Fatal("cannot decode %s data: %v", format, err)
+	}
+
+	currentEntries, err := app.Client.GetScratchpad(id)
+	if err != nil {
+		p.Fatal("cannot fetch scratchpad: %v", err)
+	}
+
+	allEntries := make(map[string]string)
+	for key, value := range currentEntries {
+		allEntries[key] = value
+	}
+	for key, value := range entries {
+		allEntries[key] = value
+	}
+
+	nbOverwrites := 0
+
+	table := NewTable([]string{"key", "current value", "new value"})
+	for _, key := range SortedScratchpadKeys(allEntries) {
+		currentValue, isCurrent := currentEntries[key]
+		value, isImported := entries[key]
+
+		switch {
+		case !isCurrent:
+			table.AddRow([]interface{}{key, "(none)", value})
+
+		case !isImported:
+			if replace {
+				table.AddRow([]interface{}{key, currentValue, "(deleted)"})
+				nbOverwrites++
+			}
+
+		case value != currentValue:
+			table.AddRow([]interface{}{key, currentValue, value})
+			nbOverwrites++
+		}
+	}
+
+	if nbOverwrites > 0 && !replace && !merge {
+		table.Write()
+		p.Fatal("%d existing entries would be overwritten: use --merge or "+
+			"--replace", nbOverwrites)
+	}
+
+	if len(table.Rows) > 0 {
+		table.Write()
+
+		prompt := fmt.Sprintf("Do you want to apply %d changes to the "+
+			"scratchpad?", len(table.Rows))
+		if Confirm(prompt) == false {
+			p.Info("import aborted")
+			return
+		}
+	}
+
+	if replace {
+		if err := app.Client.ClearScratchpad(id); err != nil {
+			p.Fatal("cannot clear scratchpad: %v", err)
+		}
+	}
+
+	for _, key := range SortedScratchpadKeys(entries) {
+		value := strings.NewReader(entries[key])
+
+		err := app.Client.SetScratchpadEntry(id, key, value)
+		if err != nil {
+			p.Fatal("cannot set scratchpad entry %q: %v", key, err)
+		}
+	}
+
+	p.Info("%d scratchpad entries imported", len(entries))
+}
+
+func cmdScratchpadEnv(p *program.Program, id string) {
+	vars := scratchpadEnvironment(p, id)
+
+	for _, v := range vars {
+		fmt.Printf("export %s=%s\n", v.Name, ShellQuote(v.Value))
+	}
+}
+
+func cmdScratchpadExec(p *program.Program, id string) {
+	args := p.TrailingArgumentValues("command")
+	if len(args) > 0 && args[0] == "--" {
+		args = args[1:]
+	}
+
+	if len(args) == 0 {
+		p.Fatal("missing command")
+	}
+
+	vars := scratchpadEnvironment(p, id)
+
+	env := os.Environ()
+	for _, v := range vars {
+		env = append(env, v.Name+"="+v.Value)
+	}
+
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Env = env
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	if err := cmd.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			p.Exit(exitErr.ExitCode())
+		}
+
+		p.Fatal("cannot execute %s: %v", args[0], err)
+	}
+}
+
+func scratchpadEnvironment(p *program.Program, id string) []EnvironmentVariable {
+	entries, err := app.Client.GetScratchpad(id)
+	if err != nil {
+		p.Fatal("cannot fetch scratchpad: %v", err)
+	}
+
+	vars, err := ScratchpadEnvironment(entries, p.OptionValue("prefix"))
+	if err != nil {
+		p.Fatal("%v", err)
+	}
+
+	return vars
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"github.com/exograd/go-program"
+)
+
+func addShellCommands() {
+	// shell
+	registry.AddCommand("shell", "start an interactive shell", cmdShell)
+}
+
+func cmdShell(p *program.Program) {
+	shell := NewShell()
+
+	// The current project is optional: it can be selected later with the
+	// "use" built-in command.
+	if id, err := app.identifyCurrentProject(); err == nil {
+		app.projectIdOption = &id
+		app.Client.ProjectId = id
+
+		if project, err := app.Client.FetchProject(id); err == nil {
+			shell.ProjectName = project.Name
+		} else {
+			p.Error("cannot fetch project %s: %v", id, err)
+		}
+	}
+
+	p.Info("Type \"help\" to list commands, \"use <project>\" to change the " +
+		"current project and \"exit\" to quit.")
+
+	if err := shell.Run(); err != nil {
+		p.Fatal("%v", err)
+	}
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/exograd/go-program"
+)
+
+func addTaskCommands() {
+	var c *Command
+
+	// show-task-output
+	c = registry.AddCommand("show-task-output", "print the output of pipeline tasks",
+		cmdShowTaskOutput)
+
+	c.AddOption("s", "step", "n", "",
+		"only print the output of a step (starting at 1)")
+	c.AddFlag("f", "follow", "keep printing output until the task finishes")
+	c.AddOption("i", "interval", "duration", "1s",
+		"the interval between two output requests when following tasks")
+
+	c.AddArgument("pipeline-id", "the identifier of the pipeline")
+	c.AddOptionalArgument("task",
+		"the identifier of the task (default: all tasks)")
+}
+
+func cmdShowTaskOutput(p *program.Program) {
+	app.IdentifyCurrentProject()
+
+	pipelineId := p.ArgumentValue("pipeline-id")
+	taskId := p.ArgumentValue("task")
+	follow := p.IsOptionSet("follow")
+
+	var step *int
+	if p.IsOptionSet("step") {
+		s := p.OptionValue("step")
+
+		i, err := strconv.Atoi(s)
+		if err != nil || i < 1 {
+			p.Fatal("invalid step %q", s)
+		}
+
+		step = &i
+	}
+
+	intervalString := p.OptionValue("interval")
+	interval, err := ParseDuration(intervalString)
+	if err != nil || interval <= 0 {
+		p.Fatal("invalid interval %q", intervalString)
+	}
+
+	tasks, err := app.Client.FetchPipelineTasks(pipelineId)
+	if err != nil {
+		p.Fatal("cannot fetch tasks: %v", err)
+	}
+
+	if taskId != "" {
+		var task *Task
+		for _, t := range tasks {
+			if t.Id == taskId || t.TaskId == taskId {
+				task = t
+				break
+			}
+		}
+
+		if task == nil {
+			p.Fatal("unknown task %q in pipeline %s", taskId, pipelineId)
+		}
+
+		tasks = Tasks{task}
+	}
+
+	for i, task := range tasks {
+		if len(tasks) > 1 {
+			if i > 0 {
+				fmt.Println("")
+			}
+
+			title := fmt.Sprintf("task %s (%s)", task.Id, task.Status)
+			fmt.Fprintln(os.Stderr, Colorize(ColorYellow, title))
+		}
+
+		if err := printTaskOutput(task, step, follow, interval); err != nil {
+			p.Fatal("cannot fetch output of task %s: %v", task.Id, err)
+		}
+	}
+}
+
+func printTaskOutput(task *Task, step *int, follow bool, interval time.Duration) error {
+	offset := 0
+
+	for {
+		output, err := app.Client.FetchTaskOutput(task.Id, step, offset)
+		if err != nil {
+			return err
+		}
+
+		os.Stdout.Write(output)
+		offset += len(output)
+
+		if !follow || task.IsFinished() {
+			break
+		}
+
+		time.Sleep(interval)
+
+		// Refresh the status after the delay so that output written
+		// between the last request and the end of the task is not lost.
+		task, err = app.Client.FetchTask(task.Id)
+		if err != nil {
+			return err
+		}
+	}
+
+	if task.FailureMessage != "" {
+		p.Error("task %s: %s", task.Id, task.FailureMessage)
+	}
+
+	return nil
+}
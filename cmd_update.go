@@ -2,30 +2,81 @@ package main
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"os"
+	"os/exec"
 	"path/filepath"
 	"runtime"
+	"strings"
+	"time"
 
 	"github.com/exograd/go-program"
 	"github.com/google/go-github/v40/github"
 )
 
 func addUpdateCommand() {
-	var c *program.Command
+	var c *Command
 
 	// update
-	c = p.AddCommand("update", "update the evcli program",
+	c = registry.AddCommand("update", "update the evcli program",
 		cmdUpdate)
 
 	c.AddOption("i", "build-id", "build-id", "",
 		"force the version to update to")
+	c.AddFlag("f", "force", "ignore the pinned version")
+	c.AddFlag("", "rollback",
+		"restore the version of evcli installed before the last update")
+	c.AddOption("", "from-file", "path", "",
+		"install a local evcli binary instead of downloading it")
+	c.AddOption("", "sha256", "hash", "",
+		"the expected SHA-256 hash of the binary installed with --from-file")
 }
 
 func cmdUpdate(p *program.Program) {
+	nbModes := 0
+	for _, name := range []string{"build-id", "rollback", "from-file"} {
+		if p.IsOptionSet(name) {
+			nbModes++
+		}
+	}
+
+	if nbModes > 1 {
+		p.Fatal("--build-id, --rollback and --from-file are incompatible")
+	}
+
+	if p.IsOptionSet("rollback") {
+		rollback()
+		return
+	}
+
+	force := p.IsOptionSet("force")
+
+	if p.IsOptionSet("from-file") {
+		if !p.IsOptionSet("sha256") {
+			p.Fatal("--from-file requires --sha256")
+		}
+
+		checksum := strings.ToLower(p.OptionValue("sha256"))
+		if !isSHA256Hash(checksum) {
+			p.Fatal("invalid sha256 hash %q", p.OptionValue("sha256"))
+		}
+
+		updateFromFile(p.OptionValue("from-file"), checksum, force)
+		return
+	} else if p.IsOptionSet("sha256") {
+		p.Fatal("--sha256 can only be used with --from-file")
+	}
+
+	if releasePublicKey == "" {
+		p.Info("evcli was built without a release public key, the " +
+			"signature of release files will not be verified")
+	}
+
 	// Determinate the identifier of the build to download and install
 	var targetBuildId *program.BuildId
 
@@ -36,10 +87,12 @@ func cmdUpdate(p *program.Program) {
 		if err := targetBuildId.Parse(s); err != nil {
 			p.Fatal("invalid build id %q: %v", s, err)
 		}
+
+		checkPinnedVersion(targetBuildId, force)
 	}
 
 	if targetBuildId == nil {
-		newBuildId, err := app.lookForLastBuild()
+		newBuildId, err := app.lookForLastBuild(force)
 		if err != nil {
 			p.Fatal("cannot find last evcli build: %v", err)
 		}
@@ -54,30 +107,159 @@ func cmdUpdate(p *program.Program) {
 
 	p.Info("updating evcli from %v to %v", app.currentBuildId(), targetBuildId)
 
-	// Find the URI of the evcli binary for the current platform
+	// Find the evcli binary for the current platform
 	osName := runtime.GOOS
 	archName := runtime.GOARCH
 
-	buildURI, err := findBuildURI(targetBuildId, osName, archName)
+	build, err := findBuild(targetBuildId, osName, archName)
+	if err != nil {
+		p.Fatal("cannot find build: %v", err)
+	}
+
+	p.Debug(1, "build uri: %s", build.URI)
+
+	// Fetch the expected checksum of the binary
+	checksum, err := fetchBuildChecksum(context.Background(), build)
 	if err != nil {
-		p.Fatal("cannot find build uri: %v", err)
+		p.Fatal("cannot fetch build checksum: %v", err)
 	}
 
-	p.Debug(1, "build uri: %s", buildURI)
+	p.Debug(1, "build checksum: %s", checksum)
 
 	// Locate the full path of the current program
 	programPath, err := locateProgramPath()
 	if err != nil {
-		p.Fatal("cannot locate program path: %w", err)
+		p.Fatal("cannot locate program path: %v", err)
 	}
 
 	// Download the new evcli binary to a temporary location
 	tmpPath := programPath + ".tmp"
 
-	if err := download(buildURI, tmpPath); err != nil {
+	hash, err := download(build.URI, tmpPath)
+	if err != nil {
 		p.Fatal("cannot download build: %v", err)
 	}
 
+	if hash != checksum {
+		tryDeleteFile(tmpPath)
+		p.Fatal("checksum mismatch for %s: expected %s, got %s",
+			build.URI, checksum, hash)
+	}
+
+	if err := os.Chmod(tmpPath, 0755); err != nil {
+		tryDeleteFile(tmpPath)
+		p.Fatal("cannot make %s executable: %v", tmpPath, err)
+	}
+
+	install(tmpPath, programPath)
+}
+
+func updateFromFile(filePath, checksum string, force bool) {
+	info, err := os.Stat(filePath)
+	if err != nil {
+		p.Fatal("cannot stat %s: %v", filePath, err)
+	}
+
+	if !info.Mode().IsRegular() {
+		p.Fatal("%s is not a regular file", filePath)
+	}
+
+	programPath, err := locateProgramPath()
+	if err != nil {
+		p.Fatal("cannot locate program path: %v", err)
+	}
+
+	// Validate a copy of the binary and not the original file, so that it
+	// cannot be modified between validation and installation.
+	tmpPath := programPath + ".tmp"
+
+	if err := copyFile(filePath, tmpPath); err != nil {
+		p.Fatal("cannot copy %s: %v", filePath, err)
+	}
+
+	// The binary is executed to obtain its build id: it must be verified
+	// first.
+	hash, err := fileSHA256(tmpPath)
+	if err != nil {
+		tryDeleteFile(tmpPath)
+		p.Fatal("cannot hash %s: %v", tmpPath, err)
+	}
+
+	if hash != checksum {
+		tryDeleteFile(tmpPath)
+		p.Fatal("checksum mismatch for %s: expected %s, got %s",
+			filePath, checksum, hash)
+	}
+
+	if err := os.Chmod(tmpPath, 0755); err != nil {
+		tryDeleteFile(tmpPath)
+		p.Fatal("cannot make %s executable: %v", tmpPath, err)
+	}
+
+	targetBuildId, err := binaryBuildId(tmpPath)
+	if err != nil {
+		tryDeleteFile(tmpPath)
+		p.Fatal("invalid evcli binary %s: %v", filePath, err)
+	}
+
+	checkPinnedVersion(targetBuildId, force)
+
+	p.Info("updating evcli from %v to %v", app.currentBuildId(), targetBuildId)
+
+	install(tmpPath, programPath)
+}
+
+// binaryBuildId runs an evcli binary to obtain its build id, making sure it
+// is a valid executable for the current platform.
+func binaryBuildId(filePath string) (*program.BuildId, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, filePath, "version")
+
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("cannot execute %s: %w", filePath, err)
+	}
+
+	fields := strings.Fields(string(output))
+	if len(fields) != 2 || fields[0] != "evcli" {
+		return nil, fmt.Errorf("unexpected version output %q",
+			strings.TrimSpace(string(output)))
+	}
+
+	var id program.BuildId
+	if err := id.Parse(fields[1]); err != nil {
+		return nil, fmt.Errorf("invalid build id %q: %w", fields[1], err)
+	}
+
+	return &id, nil
+}
+
+func checkPinnedVersion(id *program.BuildId, force bool) {
+	pinnedBuildId, err := app.pinnedBuildId()
+	if err != nil {
+		p.Fatal("%v", err)
+	}
+
+	if pinnedBuildId != nil && !force &&
+		!id.LowerThanOrEqualTo(*pinnedBuildId) {
+		p.Fatal("evcli is pinned to version %v, use --force to update "+
+			"to %v", pinnedBuildId, id)
+	}
+}
+
+func install(tmpPath, programPath string) {
+	// Keep the current binary for rollbacks
+	previousPath := programPath + ".previous"
+
+	p.Debug(1, "saving current binary to %s", previousPath)
+
+	if err := linkOrCopyFile(programPath, previousPath); err != nil {
+		tryDeleteFile(tmpPath)
+		p.Fatal("cannot save current binary: %v", err)
+	}
+
 	// Rename the temporary binary to the installation directory
 	p.Info("installing evcli to %s", programPath)
 
@@ -86,14 +268,117 @@ func cmdUpdate(p *program.Program) {
 		p.Fatal("cannot rename %s to %s: %v", tmpPath, programPath, err)
 	}
 
-	if err := os.Chmod(programPath, 0755); err != nil {
-		p.Fatal("cannot make %s executable: %v", programPath, err)
+	p.Info("evcli updated")
+	p.Info("\nRun \"evcli update --rollback\" to restore the previous " +
+		"version.")
+}
+
+func rollback() {
+	programPath, err := locateProgramPath()
+	if err != nil {
+		p.Fatal("cannot locate program path: %v", err)
 	}
 
-	p.Info("evcli updated")
+	previousPath := programPath + ".previous"
+	tmpPath := programPath + ".tmp"
+
+	if _, err := os.Stat(previousPath); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			p.Fatal("no previous version available")
+		}
+
+		p.Fatal("cannot stat %s: %v", previousPath, err)
+	}
+
+	// Swap the current and previous binaries so that a second rollback
+	// restores the current version.
+	if err := linkOrCopyFile(programPath, tmpPath); err != nil {
+		p.Fatal("cannot save current binary: %v", err)
+	}
+
+	p.Info("restoring %s", previousPath)
+
+	if err := os.Rename(previousPath, programPath); err != nil {
+		tryDeleteFile(tmpPath)
+		p.Fatal("cannot rename %s to %s: %v", previousPath, programPath, err)
+	}
+
+	if err := os.Rename(tmpPath, previousPath); err != nil {
+		p.Fatal("cannot rename %s to %s: %v", tmpPath, previousPath, err)
+	}
+
+	p.Info("evcli restored")
 }
 
-func findBuildURI(id *program.BuildId, osName, archName string) (string, error) {
+// linkOrCopyFile creates a hard link to a file, falling back to a copy if the
+// file system does not support hard links. The destination file is replaced
+// if it exists.
+func linkOrCopyFile(filePath, destPath string) error {
+	tryDeleteFile(destPath)
+
+	if err := os.Link(filePath, destPath); err == nil {
+		return nil
+	} else {
+		p.Debug(1, "cannot link %s to %s: %v", filePath, destPath, err)
+	}
+
+	return copyFile(filePath, destPath)
+}
+
+func copyFile(filePath, destPath string) error {
+	src, err := os.Open(filePath)
+	if err != nil {
+		return fmt.Errorf("cannot open %s: %w", filePath, err)
+	}
+	defer src.Close()
+
+	flags := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	dest, err := os.OpenFile(destPath, flags, 0755)
+	if err != nil {
+		return fmt.Errorf("cannot open %s: %w", destPath, err)
+	}
+	defer dest.Close()
+
+	if _, err := io.Copy(dest, src); err != nil {
+		tryDeleteFile(destPath)
+		return fmt.Errorf("cannot copy %s to %s: %w", filePath, destPath, err)
+	}
+
+	if err := dest.Sync(); err != nil {
+		tryDeleteFile(destPath)
+		return fmt.Errorf("cannot sync %s: %w", destPath, err)
+	}
+
+	return nil
+}
+
+func fileSHA256(filePath string) (string, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return "", fmt.Errorf("cannot open %s: %w", filePath, err)
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", fmt.Errorf("cannot read %s: %w", filePath, err)
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+func findBuild(id *program.BuildId, osName, archName string) (*Build, error) {
+	if uri := app.Config.Misc.UpdateURL; uri != "" {
+		p.Debug(1, "fetching update index %s", uri)
+
+		index, err := FetchUpdateIndex(context.Background(), uri)
+		if err != nil {
+			return nil, err
+		}
+
+		return index.FindBuild(id, osName, archName)
+	}
+
 	client := github.NewClient(app.HTTPClient)
 
 	ctx := context.Background()
@@ -110,51 +395,77 @@ func findBuildURI(id *program.BuildId, osName, archName string) (string, error)
 	if err != nil {
 		var githubErr *github.ErrorResponse
 		if errors.As(err, &githubErr) && githubErr.Response.StatusCode == 404 {
-			return "", fmt.Errorf("release not found")
+			return nil, fmt.Errorf("release not found")
 		}
 
-		return "", fmt.Errorf("cannot fetch release: %w", err)
+		return nil, fmt.Errorf("cannot fetch release: %w", err)
 	}
 
-	assetName := "evcli-" + osName + "-" + archName
-
-	var asset *github.ReleaseAsset
-	for _, asset = range release.Assets {
-		if asset.GetName() == assetName {
-			break
+	assetURI := func(name string) string {
+		for _, asset := range release.Assets {
+			if asset.GetName() == name {
+				return asset.GetBrowserDownloadURL()
+			}
 		}
+
+		return ""
+	}
+
+	build := Build{
+		Id:           id,
+		AssetName:    "evcli-" + osName + "-" + archName,
+		ChecksumsURI: assetURI(checksumsAssetName),
+		SignatureURI: assetURI(checksumsAssetName + ".sig"),
 	}
 
-	return asset.GetBrowserDownloadURL(), nil
+	build.URI = assetURI(build.AssetName)
+	if build.URI == "" {
+		return nil, fmt.Errorf("no build available for os %s and arch %s",
+			osName, archName)
+	}
+
+	if build.ChecksumsURI == "" {
+		return nil, fmt.Errorf("missing %s release asset",
+			checksumsAssetName)
+	}
+
+	return &build, nil
 }
 
-func download(uri, filePath string) error {
+func download(uri, filePath string) (string, error) {
 	p.Debug(2, "downloading %s to %s", uri, filePath)
 
 	flags := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
 	file, err := os.OpenFile(filePath, flags, 0644)
 	if err != nil {
-		return fmt.Errorf("cannot open file: %w", err)
+		return "", fmt.Errorf("cannot open file: %w", err)
 	}
 	defer file.Close()
 
 	req, err := http.NewRequest("GET", uri, nil)
 	if err != nil {
 		tryDeleteFile(filePath)
-		return fmt.Errorf("cannot create http request: %w", err)
+		return "", fmt.Errorf("cannot create http request: %w", err)
 	}
 
 	res, err := app.HTTPClient.Do(req)
 	if err != nil {
 		tryDeleteFile(filePath)
-		return fmt.Errorf("cannot send http request: %w", err)
+		return "", fmt.Errorf("cannot send http request: %w", err)
 	}
 	defer res.Body.Close()
 
-	n, err := io.Copy(file, res.Body)
+	if res.StatusCode != 200 {
+		tryDeleteFile(filePath)
+		return "", fmt.Errorf("request failed with status %d", res.StatusCode)
+	}
+
+	hash := sha256.New()
+
+	n, err := io.Copy(io.MultiWriter(file, hash), res.Body)
 	if err != nil {
 		tryDeleteFile(filePath)
-		return fmt.Errorf("cannot copy response body to %s: %w",
+		return "", fmt.Errorf("cannot copy response body to %s: %w",
 			filePath, err)
 	}
 
@@ -162,10 +473,10 @@ func download(uri, filePath string) error {
 
 	if err := file.Sync(); err != nil {
 		tryDeleteFile(filePath)
-		return fmt.Errorf("cannot sync %s: %w", filePath, err)
+		return "", fmt.Errorf("cannot sync %s: %w", filePath, err)
 	}
 
-	return nil
+	return hex.EncodeToString(hash.Sum(nil)), nil
 }
 
 func locateProgramPath() (string, error) {
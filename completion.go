@@ -0,0 +1,259 @@
+package main
+
+import (
+	"sort"
+	"strings"
+)
+
+// Shell completion scripts call "evcli __complete <words...>", where words
+// are the command line arguments up to the cursor, the last one being the
+// word being completed (possibly empty). Completions are printed one per
+// line, optionally followed by a tab character and a description. When there
+// is no completion, shells fall back to file name completion.
+
+const completionCommandName = "__complete"
+
+type CompletionSpec struct {
+	Options  []*CompletionOption
+	Commands map[string]*CompletionCommand
+}
+
+type CompletionCommand struct {
+	Name        string
+	Description string
+	Options     []*CompletionOption
+	Arguments   []*CompletionArgument
+}
+
+type CompletionOption struct {
+	ShortName   string
+	LongName    string
+	ValueName   string
+	Description string
+}
+
+type CompletionArgument struct {
+	Name     string
+	Trailing bool
+}
+
+type Completion struct {
+	Value       string
+	Description string
+}
+
+type Completions []Completion
+
+// CompletionContext contains the part of the command line before the word
+// being completed.
+type CompletionContext struct {
+	Command   *CompletionCommand
+	Options   map[string]string // indexed by long name
+	Arguments map[string]string
+}
+
+type CompletionSource func(*CompletionContext) (Completions, error)
+
+type CompletionSources struct {
+	Options   map[string]CompletionSource // indexed by long name
+	Arguments map[string]CompletionSource // indexed by "<command>/<argument>"
+}
+
+func (spec *CompletionSpec) CommandNames() []string {
+	names := make([]string, 0, len(spec.Commands))
+	for name := range spec.Commands {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+// CommandNameIndex returns the position of the command name in a list of
+// command line arguments, or -1 if there is no command name.
+func (spec *CompletionSpec) CommandNameIndex(args []string) int {
+	var ctx CompletionContext
+	ctx.Options = make(map[string]string)
+
+	rest, pending := consumeCompletionOptions(args, spec.Options, &ctx)
+	if pending != nil || len(rest) == 0 || rest[0] == "--" {
+		return -1
+	}
+
+	return len(args) - len(rest)
+}
+
+func (spec *CompletionSpec) Complete(words []string, sources CompletionSources) (Completions, error) {
+	if len(words) == 0 {
+		words = []string{""}
+	}
+
+	current := words[len(words)-1]
+	args := words[:len(words)-1]
+
+	ctx := CompletionContext{
+		Options:   make(map[string]string),
+		Arguments: make(map[string]string),
+	}
+
+	// Global options and command name
+	args, pending := consumeCompletionOptions(args, spec.Options, &ctx)
+	if pending != nil {
+		return completeOptionValue(pending, current, &ctx, sources)
+	}
+
+	if len(args) == 0 {
+		if strings.HasPrefix(current, "-") {
+			return completeOptions(spec.Options, current, &ctx), nil
+		}
+
+		var cs Completions
+		for _, name := range spec.CommandNames() {
+			cs = append(cs, Completion{
+				Value:       name,
+				Description: spec.Commands[name].Description,
+			})
+		}
+
+		return cs.Filter(current), nil
+	}
+
+	command, found := spec.Commands[args[0]]
+	if !found {
+		return nil, nil
+	}
+
+	ctx.Command = command
+
+	// Command options
+	options := append([]*CompletionOption{}, spec.Options...)
+	options = append(options, command.Options...)
+
+	args, pending = consumeCompletionOptions(args[1:], options, &ctx)
+	if pending != nil {
+		return completeOptionValue(pending, current, &ctx, sources)
+	}
+
+	if len(args) == 0 && strings.HasPrefix(current, "-") {
+		return completeOptions(options, current, &ctx), nil
+	}
+
+	// Arguments
+	var argument *CompletionArgument
+
+	for i, a := range command.Arguments {
+		if a.Trailing || i == len(args) {
+			argument = a
+			break
+		}
+
+		ctx.Arguments[a.Name] = args[i]
+	}
+
+	if argument == nil {
+		return nil, nil
+	}
+
+	source, found := sources.Arguments[command.Name+"/"+argument.Name]
+	if !found {
+		return nil, nil
+	}
+
+	cs, err := source(&ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return cs.Filter(current), nil
+}
+
+// consumeCompletionOptions skips options at the beginning of a list of
+// arguments. If the last argument is an option expecting a value, this option
+// is returned since the word being completed is its value.
+func consumeCompletionOptions(args []string, options []*CompletionOption, ctx *CompletionContext) ([]string, *CompletionOption) {
+	for len(args) > 0 {
+		arg := args[0]
+
+		isShort := len(arg) == 2 && arg[0] == '-' && arg[1] != '-'
+		isLong := len(arg) > 2 && arg[0:2] == "--"
+
+		if arg == "--" || !(isShort || isLong) {
+			break
+		}
+
+		args = args[1:]
+
+		option := findCompletionOption(options, strings.TrimLeft(arg, "-"))
+		if option == nil {
+			continue
+		}
+
+		if option.ValueName == "" {
+			ctx.Options[option.LongName] = ""
+			continue
+		}
+
+		if len(args) == 0 {
+			return args, option
+		}
+
+		ctx.Options[option.LongName] = args[0]
+		args = args[1:]
+	}
+
+	return args, nil
+}
+
+func findCompletionOption(options []*CompletionOption, name string) *CompletionOption {
+	for _, option := range options {
+		if name == option.LongName || name == option.ShortName {
+			return option
+		}
+	}
+
+	return nil
+}
+
+func completeOptions(options []*CompletionOption, current string, ctx *CompletionContext) Completions {
+	var cs Completions
+
+	for _, option := range options {
+		if _, found := ctx.Options[option.LongName]; found {
+			continue
+		}
+
+		cs = append(cs, Completion{
+			Value:       "--" + option.LongName,
+			Description: option.Description,
+		})
+	}
+
+	return cs.Filter(current)
+}
+
+func completeOptionValue(option *CompletionOption, current string, ctx *CompletionContext, sources CompletionSources) (Completions, error) {
+	source, found := sources.Options[option.LongName]
+	if !found {
+		return nil, nil
+	}
+
+	cs, err := source(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return cs.Filter(current), nil
+}
+
+func (cs Completions) Filter(prefix string) Completions {
+	var filtered Completions
+
+	for _, c := range cs {
+		if strings.HasPrefix(c.Value, prefix) {
+			filtered = append(filtered, c)
+		}
+	}
+
+	return filtered
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/exograd/go-program"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompletionSpec(t *testing.T) {
+	assert := assert.New(t)
+
+	r := NewRegistry(program.NewProgram("test", ""))
+	r.AddOption("p", "project-name", "name", "", "")
+
+	c := r.AddCommand("foo", "", nil)
+	c.AddFlag("f", "force", "")
+	c.AddArgument("a", "")
+	c.AddTrailingArgument("b", "")
+
+	r.AddCommand("bar", "", nil)
+
+	spec := r.Spec
+
+	sources := CompletionSources{
+		Options: map[string]CompletionSource{
+			"project-name": func(ctx *CompletionContext) (Completions, error) {
+				return Completions{{Value: "x"}, {Value: "y"}}, nil
+			},
+		},
+		Arguments: map[string]CompletionSource{
+			"foo/b": func(ctx *CompletionContext) (Completions, error) {
+				return Completions{{Value: ctx.Arguments["a"] + "1"}}, nil
+			},
+		},
+	}
+
+	complete := func(words ...string) []string {
+		cs, err := spec.Complete(words, sources)
+		if !assert.NoError(err) {
+			return nil
+		}
+
+		var values []string
+		for _, c := range cs {
+			values = append(values, c.Value)
+		}
+
+		return values
+	}
+
+	assert.Equal([]string{"bar", "foo", "help"}, complete(""))
+	assert.Equal([]string{"foo"}, complete("-p", "x", "f"))
+	assert.Equal([]string{"x", "y"}, complete("-p", ""))
+	assert.Equal([]string{"y"}, complete("foo", "--project-name", "y"))
+	assert.Equal([]string{"--force"}, complete("foo", "--f"))
+	assert.Equal([]string{"--debug", "--help", "--project-name", "--quiet"},
+		complete("foo", "--force", "--"))
+	assert.Nil(complete("foo", ""))
+	assert.Equal([]string{"a1"}, complete("foo", "-f", "a", ""))
+	assert.Equal([]string{"a1"}, complete("foo", "a", "b", "a"))
+	assert.Nil(complete("baz", ""))
+}
@@ -15,6 +15,8 @@ type Config struct {
 	Interface InterfaceConfig `json:"interface,omitempty"`
 	API       APIConfig       `json:"api,omitempty"`
 	Misc      MiscConfig      `json:"misc,omitempty"`
+
+	Aliases map[string][]string `json:"aliases,omitempty"`
 }
 
 type InterfaceConfig struct {
@@ -22,12 +24,22 @@ type InterfaceConfig struct {
 }
 
 type APIConfig struct {
-	Endpoint string `json:"endpoint,omitempty"`
-	Key      string `json:"key,omitempty"`
+	Endpoint           string `json:"endpoint,omitempty"`
+	Key                string `json:"key,omitempty"`
+	KeyFile            string `json:"key_file,omitempty"`
+	KeyCommand         string `json:"key_command,omitempty"`
+	KeyKeyring         bool   `json:"key_keyring,omitempty"`
+	CAFile             string `json:"ca_file,omitempty"`
+	ClientCert         string `json:"client_cert,omitempty"`
+	ClientKey          string `json:"client_key,omitempty"`
+	InsecureSkipVerify bool   `json:"insecure_skip_verify,omitempty"`
+	Proxy              string `json:"proxy,omitempty"`
 }
 
 type MiscConfig struct {
-	DisableUpdateCheck bool `json:"disable_update_check,omitempty"`
+	DisableUpdateCheck bool   `json:"disable_update_check,omitempty"`
+	PinnedVersion      string `json:"pinned_version,omitempty"`
+	UpdateURL          string `json:"update_url,omitempty"`
 }
 
 func LoadConfig() (*Config, error) {
@@ -151,6 +163,25 @@ func (c *Config) WriteFile(filePath string) error {
 	return nil
 }
 
+// Redacted returns a copy of the configuration where the values of all
+// sensitive entries are masked.
+func (c *Config) Redacted() *Config {
+	c2 := *c
+
+	for _, e := range ConfigEntries {
+		if e.Sensitive && e.Get(&c2) != "" {
+			if err := e.Set(&c2, RedactedValue); err != nil {
+				p.Fatal("cannot redact entry %q: %v", e.Name, err)
+			}
+		}
+	}
+
+	// Proxy URIs are not sensitive, but they often contain credentials
+	c2.API.Proxy = RedactURI(c2.API.Proxy)
+
+	return &c2
+}
+
 func (c *Config) GetEntry(name string) (string, error) {
 	e, found := ConfigEntries[name]
 	if !found {
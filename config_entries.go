@@ -2,15 +2,19 @@ package main
 
 import (
 	"fmt"
+	"net/url"
 	"strings"
+
+	"github.com/exograd/go-program"
 )
 
 var ConfigEntries map[string]ConfigEntry
 
 type ConfigEntry struct {
-	Name string
-	Get  func(*Config) string
-	Set  func(*Config, string) error
+	Name      string
+	Sensitive bool
+	Get       func(*Config) string
+	Set       func(*Config, string) error
 }
 
 func init() {
@@ -23,12 +27,71 @@ func init() {
 			},
 		},
 		ConfigEntry{
-			Name: "api.key",
-			Get:  func(c *Config) string { return c.API.Key },
+			Name:      "api.key",
+			Sensitive: true,
+			Get:       func(c *Config) string { return c.API.Key },
 			Set: func(c *Config, s string) error {
 				return setString(s, &c.API.Key)
 			},
 		},
+		ConfigEntry{
+			Name: "api.key_file",
+			Get:  func(c *Config) string { return c.API.KeyFile },
+			Set: func(c *Config, s string) error {
+				return setString(s, &c.API.KeyFile)
+			},
+		},
+		ConfigEntry{
+			Name: "api.key_command",
+			Get:  func(c *Config) string { return c.API.KeyCommand },
+			Set: func(c *Config, s string) error {
+				return setString(s, &c.API.KeyCommand)
+			},
+		},
+		ConfigEntry{
+			Name: "api.key_keyring",
+			Get:  func(c *Config) string { return fmtBool(c.API.KeyKeyring) },
+			Set: func(c *Config, s string) error {
+				return setBool(s, &c.API.KeyKeyring)
+			},
+		},
+		ConfigEntry{
+			Name: "api.ca_file",
+			Get:  func(c *Config) string { return c.API.CAFile },
+			Set: func(c *Config, s string) error {
+				return setString(s, &c.API.CAFile)
+			},
+		},
+		ConfigEntry{
+			Name: "api.client_cert",
+			Get:  func(c *Config) string { return c.API.ClientCert },
+			Set: func(c *Config, s string) error {
+				return setString(s, &c.API.ClientCert)
+			},
+		},
+		ConfigEntry{
+			Name: "api.client_key",
+			Get:  func(c *Config) string { return c.API.ClientKey },
+			Set: func(c *Config, s string) error {
+				return setString(s, &c.API.ClientKey)
+			},
+		},
+		ConfigEntry{
+			Name: "api.insecure_skip_verify",
+			Get: func(c *Config) string {
+				return fmtBool(c.API.InsecureSkipVerify)
+			},
+			Set: func(c *Config, s string) error {
+				return setBool(s, &c.API.InsecureSkipVerify)
+			},
+		},
+		ConfigEntry{
+			Name: "api.proxy",
+			Get:  func(c *Config) string { return c.API.Proxy },
+			Set: func(c *Config, s string) error {
+				return setString(s, &c.API.Proxy)
+			},
+		},
 		ConfigEntry{
 			Name: "interface.color",
 			Get:  func(c *Config) string { return fmtBool(c.Interface.Color) },
@@ -45,6 +108,20 @@ func init() {
 				return setBool(s, &c.Misc.DisableUpdateCheck)
 			},
 		},
+		ConfigEntry{
+			Name: "misc.pinned_version",
+			Get:  func(c *Config) string { return c.Misc.PinnedVersion },
+			Set: func(c *Config, s string) error {
+				return setBuildId(s, &c.Misc.PinnedVersion)
+			},
+		},
+		ConfigEntry{
+			Name: "misc.update_url",
+			Get:  func(c *Config) string { return c.Misc.UpdateURL },
+			Set: func(c *Config, s string) error {
+				return setHTTPURL(s, &c.Misc.UpdateURL)
+			},
+		},
 	}
 
 	ConfigEntries = make(map[string]ConfigEntry)
@@ -79,3 +156,32 @@ func setBool(s string, pb *bool) error {
 
 	return nil
 }
+
+func setHTTPURL(s string, ps *string) error {
+	s = strings.TrimSpace(s)
+
+	if s != "" {
+		uri, err := url.Parse(s)
+		if err != nil || (uri.Scheme != "http" && uri.Scheme != "https") ||
+			uri.Host == "" {
+			return fmt.Errorf("%q is not a valid http or https url", s)
+		}
+	}
+
+	*ps = s
+	return nil
+}
+
+func setBuildId(s string, ps *string) error {
+	s = strings.TrimSpace(s)
+
+	if s != "" {
+		var id program.BuildId
+		if err := id.Parse(s); err != nil {
+			return fmt.Errorf("%q is not a valid build id", s)
+		}
+	}
+
+	*ps = s
+	return nil
+}
@@ -0,0 +1,722 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"golang.org/x/sys/unix"
+	"golang.org/x/term"
+)
+
+// The dashboard is a full screen terminal interface. All the state is owned
+// by the main loop in Run: API requests are executed in separate goroutines
+// which send back functions applying their result to the dashboard.
+//
+// The dashboard can be used in the shell: all goroutines stop when it is
+// closed, and the standard input is only read when data are available so
+// that no input is consumed once the shell is back.
+
+type DashboardView int
+
+const (
+	DashboardViewPipelines DashboardView = iota
+	DashboardViewTasks
+)
+
+type Dashboard struct {
+	Filter   *PipelineFilter
+	Interval time.Duration
+
+	view DashboardView
+
+	pipelines      Pipelines
+	pipelineIdx    int
+	pipelineOffset int
+	refreshTime    time.Time
+	refreshing     bool
+
+	tasks           Tasks
+	tasksPipelineId string
+	taskIdx         int
+
+	scratchpad           map[string]string
+	scratchpadPipelineId string
+
+	message      string
+	messageError bool
+
+	pendingAction *DashboardAction
+
+	updates chan func()
+	done    chan struct{}
+}
+
+type DashboardAction struct {
+	Verb     string
+	PastVerb string
+	Run      func(string) error
+}
+
+func NewDashboard(filter *PipelineFilter, interval time.Duration) *Dashboard {
+	d := Dashboard{
+		Filter:   filter,
+		Interval: interval,
+
+		updates: make(chan func()),
+		done:    make(chan struct{}),
+	}
+
+	return &d
+}
+
+func (d *Dashboard) Run() error {
+	stdin := int(os.Stdin.Fd())
+	stdout := int(os.Stdout.Fd())
+
+	if !term.IsTerminal(stdin) || !term.IsTerminal(stdout) {
+		return fmt.Errorf("the dashboard requires a terminal")
+	}
+
+	state, err := term.MakeRaw(stdin)
+	if err != nil {
+		return fmt.Errorf("cannot configure terminal: %w", err)
+	}
+	defer term.Restore(stdin, state)
+
+	// Use the alternate screen buffer and hide the cursor
+	os.Stdout.WriteString("\x1b[?1049h\x1b[?25l")
+	defer os.Stdout.WriteString("\x1b[?25h\x1b[?1049l")
+
+	defer close(d.done)
+
+	keys := make(chan string)
+	go readDashboardKeys(stdin, keys, d.done)
+
+	refreshTicker := time.NewTicker(d.Interval)
+	defer refreshTicker.Stop()
+
+	// The terminal can be resized at any moment; redrawing regularly is
+	// simpler and more portable than handling SIGWINCH.
+	renderTicker := time.NewTicker(time.Second)
+	defer renderTicker.Stop()
+
+	d.refresh()
+
+	for {
+		if err := d.render(stdout); err != nil {
+			return err
+		}
+
+		select {
+		case key, ok := <-keys:
+			if !ok || d.handleKey(key) {
+				return nil
+			}
+
+		case update := <-d.updates:
+			update()
+
+		case <-refreshTicker.C:
+			d.refresh()
+
+		case <-renderTicker.C:
+		}
+	}
+}
+
+func readDashboardKeys(fd int, keys chan<- string, done <-chan struct{}) {
+	defer close(keys)
+
+	buf := make([]byte, 64)
+	fds := []unix.PollFd{{Fd: int32(fd), Events: unix.POLLIN}}
+
+	for {
+		nbReady, err := unix.Poll(fds, 100)
+		if err != nil && !errors.Is(err, unix.EINTR) {
+			return
+		}
+
+		select {
+		case <-done:
+			return
+		default:
+		}
+
+		if nbReady <= 0 {
+			continue
+		}
+
+		n, err := os.Stdin.Read(buf)
+		if err != nil {
+			return
+		}
+
+		data := buf[:n]
+
+		for len(data) > 0 {
+			var key string
+
+			switch {
+			case bytes.HasPrefix(data, []byte("\x1b[A")):
+				key, data = "up", data[3:]
+			case bytes.HasPrefix(data, []byte("\x1b[B")):
+				key, data = "down", data[3:]
+			case bytes.HasPrefix(data, []byte("\x1b[C")):
+				key, data = "right", data[3:]
+			case bytes.HasPrefix(data, []byte("\x1b[D")):
+				key, data = "left", data[3:]
+			case data[0] == 0x1b:
+				// Unknown escape sequences are ignored entirely
+				key, data = "escape", nil
+			case data[0] == '\r' || data[0] == '\n':
+				key, data = "enter", data[1:]
+			case data[0] == 0x7f || data[0] == 0x08:
+				key, data = "backspace", data[1:]
+			case data[0] == 0x03:
+				key, data = "ctrl-c", data[1:]
+			default:
+				r, size := utf8.DecodeRune(data)
+				key, data = string(r), data[size:]
+			}
+
+			select {
+			case keys <- key:
+			case <-done:
+				return
+			}
+		}
+	}
+}
+
+func (d *Dashboard) actions() map[string]*DashboardAction {
+	return map[string]*DashboardAction{
+		"a": {"abort", "aborted", app.Client.AbortPipeline},
+		"r": {"restart", "restarted", app.Client.RestartPipeline},
+		"f": {"restart from failure", "restarted from failure",
+			app.Client.RestartPipelineFromFailure},
+	}
+}
+
+// handleKey processes a key press and returns true if the dashboard must be
+// closed.
+func (d *Dashboard) handleKey(key string) bool {
+	if key == "ctrl-c" {
+		return true
+	}
+
+	if action := d.pendingAction; action != nil {
+		d.pendingAction = nil
+
+		if key == "y" {
+			d.runAction(action)
+		} else {
+			d.setMessage("%s cancelled", action.Verb)
+		}
+
+		return false
+	}
+
+	switch key {
+	case "q":
+		return true
+
+	case "up", "k":
+		d.moveSelection(-1)
+
+	case "down", "j":
+		d.moveSelection(1)
+
+	case "enter", "right":
+		if d.view == DashboardViewPipelines && d.selectedPipeline() != nil {
+			d.view = DashboardViewTasks
+			d.taskIdx = 0
+			d.loadDetails()
+		}
+
+	case "escape", "backspace", "left":
+		d.view = DashboardViewPipelines
+
+	default:
+		action, found := d.actions()[key]
+		if !found {
+			break
+		}
+
+		pipeline := d.selectedPipeline()
+		if pipeline == nil {
+			break
+		}
+
+		d.pendingAction = action
+		d.setMessage("%s pipeline %s (%s)? [y/n]", action.Verb, pipeline.Id,
+			pipeline.Name)
+	}
+
+	return false
+}
+
+func (d *Dashboard) moveSelection(delta int) {
+	if d.view == DashboardViewTasks {
+		d.taskIdx = clampIndex(d.taskIdx+delta, len(d.tasks))
+		return
+	}
+
+	idx := clampIndex(d.pipelineIdx+delta, len(d.pipelines))
+	if idx != d.pipelineIdx {
+		d.pipelineIdx = idx
+		d.loadDetails()
+	}
+}
+
+func clampIndex(i, n int) int {
+	if i >= n {
+		i = n - 1
+	}
+
+	if i < 0 {
+		i = 0
+	}
+
+	return i
+}
+
+func (d *Dashboard) selectedPipeline() *Pipeline {
+	if d.pipelineIdx >= len(d.pipelines) {
+		return nil
+	}
+
+	return d.pipelines[d.pipelineIdx]
+}
+
+func (d *Dashboard) setMessage(format string, args ...interface{}) {
+	d.message = fmt.Sprintf(format, args...)
+	d.messageError = false
+}
+
+func (d *Dashboard) setError(format string, args ...interface{}) {
+	d.message = fmt.Sprintf(format, args...)
+	d.messageError = true
+}
+
+func (d *Dashboard) refresh() {
+	if d.refreshing {
+		return
+	}
+
+	d.refreshing = true
+
+	go func() {
+		pipelines, err := app.Client.FetchPipelines(d.Filter)
+
+		d.update(func() {
+			d.refreshing = false
+
+			if err != nil {
+				d.setError("cannot fetch pipelines: %v", err)
+				return
+			}
+
+			d.setPipelines(pipelines)
+			d.refreshTime = time.Now()
+
+			d.loadDetails()
+		})
+	}()
+}
+
+// update sends a function applying the result of a request to the main loop,
+// unless the dashboard has been closed.
+func (d *Dashboard) update(f func()) {
+	select {
+	case d.updates <- f:
+	case <-d.done:
+	}
+}
+
+func (d *Dashboard) setPipelines(pipelines Pipelines) {
+	// Keep the same pipeline selected if it is still listed
+	if selected := d.selectedPipeline(); selected != nil {
+		for i, pipeline := range pipelines {
+			if pipeline.Id == selected.Id {
+				d.pipelineIdx = i
+				break
+			}
+		}
+	}
+
+	d.pipelines = pipelines
+	d.pipelineIdx = clampIndex(d.pipelineIdx, len(pipelines))
+}
+
+func (d *Dashboard) loadDetails() {
+	pipeline := d.selectedPipeline()
+	if pipeline == nil {
+		return
+	}
+
+	id := pipeline.Id
+
+	go func() {
+		scratchpad, err := app.Client.GetScratchpad(id)
+
+		d.update(func() {
+			if err != nil {
+				d.setError("cannot fetch scratchpad: %v", err)
+				return
+			}
+
+			d.scratchpad = scratchpad
+			d.scratchpadPipelineId = id
+		})
+	}()
+
+	if d.view != DashboardViewTasks {
+		return
+	}
+
+	go func() {
+		tasks, err := app.Client.FetchPipelineTasks(id)
+
+		d.update(func() {
+			if err != nil {
+				d.setError("cannot fetch tasks: %v", err)
+				return
+			}
+
+			d.tasks = tasks
+			d.tasksPipelineId = id
+			d.taskIdx = clampIndex(d.taskIdx, len(tasks))
+		})
+	}()
+}
+
+func (d *Dashboard) runAction(action *DashboardAction) {
+	pipeline := d.selectedPipeline()
+	if pipeline == nil {
+		return
+	}
+
+	id := pipeline.Id
+
+	d.setMessage("%s pipeline %s...", action.Verb, id)
+
+	go func() {
+		err := action.Run(id)
+
+		d.update(func() {
+			if err != nil {
+				d.setError("cannot %s pipeline %s: %v", action.Verb, id, err)
+				return
+			}
+
+			d.setMessage("pipeline %s %s", id, action.PastVerb)
+			d.refresh()
+		})
+	}()
+}
+
+func (d *Dashboard) render(fd int) error {
+	width, height, err := term.GetSize(fd)
+	if err != nil {
+		return fmt.Errorf("cannot obtain terminal size: %w", err)
+	}
+
+	if height < 4 {
+		return nil
+	}
+
+	bodyHeight := height - 3
+
+	// Main panel and scratchpad side panel if there is enough space
+	sideWidth := 0
+	if width >= 80 {
+		sideWidth = width / 3
+	}
+
+	mainWidth := width
+	if sideWidth > 0 {
+		mainWidth = width - sideWidth - 1
+	}
+
+	var main []string
+	if d.view == DashboardViewTasks {
+		main = d.renderTasks(mainWidth, bodyHeight)
+	} else {
+		main = d.renderPipelines(mainWidth, bodyHeight)
+	}
+
+	var side []string
+	if sideWidth > 0 {
+		side = d.renderScratchpad(sideWidth, bodyHeight)
+	}
+
+	var buf bytes.Buffer
+
+	buf.WriteString("\x1b[H")
+
+	writeLine := func(s string) {
+		buf.WriteString(s)
+		buf.WriteString("\x1b[K\r\n")
+	}
+
+	writeLine(Colorize(ColorCyan, fitText(d.header(), width)))
+
+	for i := 0; i < bodyHeight; i++ {
+		line := colorizeStatuses(fitText(dashboardLine(main, i), mainWidth))
+
+		if sideWidth > 0 {
+			line += "|" + fitText(dashboardLine(side, i), sideWidth)
+		}
+
+		writeLine(line)
+	}
+
+	message := fitText(d.message, width)
+	if d.messageError {
+		message = Colorize(ColorRed, message)
+	}
+	writeLine(message)
+
+	help := "up/down select  enter tasks  esc back  a abort  r restart  " +
+		"f restart from failure  q quit"
+	buf.WriteString(fitText(help, width))
+	buf.WriteString("\x1b[K")
+
+	_, err = os.Stdout.Write(buf.Bytes())
+	return err
+}
+
+func dashboardLine(lines []string, i int) string {
+	if i >= len(lines) {
+		return ""
+	}
+
+	return lines[i]
+}
+
+func (d *Dashboard) header() string {
+	header := "evcli dashboard"
+
+	if projectId := app.Client.ProjectId; projectId != "" {
+		header += "  project " + projectId
+	}
+
+	header += fmt.Sprintf("  %d pipelines", len(d.pipelines))
+
+	if !d.refreshTime.IsZero() {
+		header += "  updated " + d.refreshTime.Format("15:04:05")
+	}
+
+	if d.refreshing {
+		header += "  refreshing..."
+	}
+
+	return header
+}
+
+// colorizeStatuses highlights pipeline and task statuses. Lines are
+// colorized after being truncated so that escape sequences do not count in
+// the width of the line.
+func colorizeStatuses(line string) string {
+	colors := map[string]Color{
+		PipelineStatusCreated:    ColorBlue,
+		PipelineStatusStarted:    ColorBlue,
+		PipelineStatusAborted:    ColorYellow,
+		PipelineStatusSuccessful: ColorGreen,
+		PipelineStatusFailed:     ColorRed,
+	}
+
+	for status, color := range colors {
+		padded := " " + status + " "
+		line = strings.Replace(line, padded,
+			" "+Colorize(color, status)+" ", 1)
+	}
+
+	return line
+}
+
+func (d *Dashboard) renderPipelines(width, height int) []string {
+	if len(d.pipelines) == 0 {
+		if d.refreshTime.IsZero() {
+			return []string{"loading pipelines..."}
+		}
+
+		return []string{"no pipeline found"}
+	}
+
+	// Scroll to keep the selected pipeline visible
+	if d.pipelineIdx < d.pipelineOffset {
+		d.pipelineOffset = d.pipelineIdx
+	} else if d.pipelineIdx >= d.pipelineOffset+height-1 {
+		d.pipelineOffset = d.pipelineIdx - height + 2
+	}
+
+	rows := [][]string{{"", "id", "name", "status", "start time", "duration"}}
+
+	for i, pipeline := range d.pipelines {
+		marker := " "
+		if i == d.pipelineIdx {
+			marker = ">"
+		}
+
+		var startTime, duration string
+
+		if pipeline.StartTime != nil {
+			startTime = pipeline.StartTime.In(app.Location).
+				Format("2006-01-02 15:04:05")
+		}
+
+		if pipelineDuration := pipeline.Duration(); pipelineDuration != nil {
+			duration = FormatDuration(*pipelineDuration)
+		}
+
+		rows = append(rows, []string{marker, pipeline.Id, pipeline.Name,
+			pipeline.Status, startTime, duration})
+	}
+
+	lines := formatDashboardRows(rows)
+
+	visible := []string{lines[0]}
+	for i := d.pipelineOffset + 1; i < len(lines) && len(visible) < height; i++ {
+		visible = append(visible, lines[i])
+	}
+
+	return visible
+}
+
+func (d *Dashboard) renderTasks(width, height int) []string {
+	pipeline := d.selectedPipeline()
+	if pipeline == nil {
+		return nil
+	}
+
+	lines := []string{
+		fmt.Sprintf("pipeline %s (%s)  %s", pipeline.Name, pipeline.Id,
+			pipeline.Status),
+		"",
+	}
+
+	if d.tasksPipelineId != pipeline.Id {
+		return append(lines, "loading tasks...")
+	}
+
+	rows := [][]string{{"", "id", "task", "status", "duration"}}
+
+	for i, task := range d.tasks {
+		marker := " "
+		if i == d.taskIdx {
+			marker = ">"
+		}
+
+		var duration string
+		if taskDuration := task.Duration(); taskDuration != nil {
+			duration = FormatDuration(*taskDuration)
+		}
+
+		rows = append(rows, []string{marker, task.Id, task.TaskId,
+			task.Status, duration})
+	}
+
+	lines = append(lines, formatDashboardRows(rows)...)
+
+	if d.taskIdx < len(d.tasks) {
+		task := d.tasks[d.taskIdx]
+
+		if task.FailureMessage != "" {
+			lines = append(lines, "", "failure:")
+			lines = append(lines, wrapText(task.FailureMessage, width)...)
+		}
+	}
+
+	return lines
+}
+
+func (d *Dashboard) renderScratchpad(width, height int) []string {
+	lines := []string{"scratchpad", ""}
+
+	pipeline := d.selectedPipeline()
+	if pipeline == nil || d.scratchpadPipelineId != pipeline.Id {
+		return lines
+	}
+
+	if len(d.scratchpad) == 0 {
+		return append(lines, "no entry")
+	}
+
+	keys := make([]string, 0, len(d.scratchpad))
+	for key := range d.scratchpad {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		value := strings.ReplaceAll(d.scratchpad[key], "\n", " ")
+		lines = append(lines, wrapText(key+": "+value, width)...)
+	}
+
+	return lines
+}
+
+func formatDashboardRows(rows [][]string) []string {
+	widths := make([]int, len(rows[0]))
+
+	for _, row := range rows {
+		for j, value := range row {
+			if n := utf8.RuneCountInString(value); n > widths[j] {
+				widths[j] = n
+			}
+		}
+	}
+
+	lines := make([]string, len(rows))
+
+	for i, row := range rows {
+		var buf strings.Builder
+
+		for j, value := range row {
+			if j > 0 {
+				buf.WriteString(" ")
+			}
+
+			buf.WriteString(fitText(value, widths[j]))
+		}
+
+		lines[i] = buf.String() + " "
+	}
+
+	return lines
+}
+
+// fitText truncates or pads a string so that it is exactly width characters
+// long.
+func fitText(s string, width int) string {
+	n := utf8.RuneCountInString(s)
+
+	if n > width {
+		return string([]rune(s)[:width])
+	}
+
+	return s + strings.Repeat(" ", width-n)
+}
+
+func wrapText(s string, width int) []string {
+	if width <= 0 {
+		return nil
+	}
+
+	var lines []string
+
+	runes := []rune(s)
+	for len(runes) > width {
+		lines = append(lines, string(runes[:width]))
+		runes = runes[width:]
+	}
+
+	return append(lines, string(runes))
+}
@@ -1,104 +1,234 @@
 package main
 
 import (
+	"os"
+	"time"
+
 	"github.com/exograd/go-program"
 )
 
 var (
-	p   *program.Program
-	app *App
+	p        *program.Program
+	registry *Registry
+	app      *App
 
 	buildId string
 
 	skipConfirmations bool
 	colorOutput       bool
+
+	traceHTTP   bool
+	harRecorder *HARRecorder
 )
 
 func main() {
-	// Command line
-	p = program.NewProgram("evcli", "client for the eventline service")
+	newProgram()
+
+	// Shell completion is not a regular command since it must not appear in
+	// the help message; it is handled before the command line is parsed.
+	if len(os.Args) > 1 && os.Args[1] == completionCommandName {
+		initialize(loadConfig())
+		cmdComplete(os.Args[2:])
+		return
+	}
+
+	expandCommandLineAlias(readConfigAliases())
+	plugin := addPluginCommand()
+
+	p.ParseCommandLine()
+
+	initialize(loadConfig())
+
+	name := p.CommandName()
+
+	// Plugins load the api key themselves if it is available
+	loadAPIKey := plugin == nil || name != plugin.Name
+	for _, cmdName := range noAPIKeyCommands() {
+		if name == cmdName {
+			loadAPIKey = false
+			break
+		}
+	}
+
+	if loadAPIKey {
+		app.LoadAPIKey()
+	}
 
-	p.AddFlag("y", "yes", "skip all confirmations")
-	p.AddFlag("", "no-color", "do not use colors")
+	var updateCheck *UpdateCheck
+	// Child processes started by evcli itself (e.g. plugins) do not check
+	// for updates since their parent already does.
+	checkForUpdates := !app.Config.Misc.DisableUpdateCheck &&
+		os.Getenv("EVCLI_DISABLE_UPDATE_CHECK") == "" && name != "update"
 
-	p.AddOption("", "project-id", "id", "",
+	if checkForUpdates {
+		updateCheck = app.StartUpdateCheck()
+	}
+
+	p.Run()
+
+	if updateCheck != nil {
+		updateCheck.PrintNotice()
+	}
+}
+
+// newProgram creates the program and declares its options and commands. The
+// shell creates a new program for each command it executes.
+func newProgram() {
+	p = program.NewProgram("evcli", "client for the eventline service")
+	registry = NewRegistry(p)
+
+	registry.AddFlag("y", "yes", "skip all confirmations")
+	registry.AddFlag("", "no-color", "do not use colors")
+	registry.AddFlag("", "trace",
+		"print all http requests and responses (bodies may contain "+
+			"secrets such as scratchpad values)")
+	registry.AddOption("", "har", "path", "",
+		"record all http requests and responses to a HAR file (bodies "+
+			"may contain secrets such as scratchpad values)")
+
+	registry.AddOption("", "ca-file", "path", "",
+		"a file containing additional ca certificates")
+	registry.AddOption("", "client-cert", "path", "",
+		"the client certificate file used for tls authentication")
+	registry.AddOption("", "client-key", "path", "",
+		"the client key file used for tls authentication")
+	registry.AddFlag("", "insecure-skip-verify",
+		"disable tls certificate verification (dangerous)")
+	registry.AddOption("", "proxy", "uri", "",
+		"the uri of the http proxy used for all requests")
+
+	registry.AddOption("", "project-id", "id", "",
 		"the identifier of the current project")
-	p.AddOption("p", "project-name", "name", "",
+	registry.AddOption("p", "project-name", "name", "",
 		"the name of the current project")
+	registry.AddOption("", "timezone", "name", "",
+		"the timezone used to interpret local dates and times")
 
 	addConfigCommands()
 	addUpdateCommand()
 	addProjectCommands()
 	addCommandCommands()
 	addPipelineCommands()
+	addTaskCommands()
 	addScratchpadCommands()
 	addEventCommands()
+	addCompletionCommands()
+	addShellCommands()
+	addDashboardCommands()
+	addPluginCommands()
 
-	p.AddCommand("version", "print the version of evcli and exit", cmdVersion)
-
-	p.ParseCommandLine()
+	registry.AddCommand("version", "print the version of evcli and exit",
+		cmdVersion)
+}
 
-	// Config
-	skipConfirmations = p.IsOptionSet("yes")
+func noAPIKeyCommands() []string {
+	return []string{
+		"completion",
+		"delete-alias",
+		"get-config",
+		"help",
+		"list-aliases",
+		"list-plugins",
+		"set-alias",
+		"set-config",
+		"show-config",
+		"update",
+		"version",
+	}
+}
 
+func loadConfig() *Config {
 	config, err := LoadConfig()
 	if err != nil {
 		p.Fatal("cannot load configuration: %v", err)
 	}
 
-	colorOutput = config.Interface.Color && !p.IsOptionSet("no-color")
+	return config
+}
 
-	// Application
-	client, err := NewClient(config)
-	if err != nil {
-		p.Fatal("cannot create api client: %v", err)
+func initialize(config *Config) {
+	initializeOptions(config)
+
+	if p.IsOptionSet("har") {
+		harRecorder = NewHARRecorder(p.OptionValue("har"))
 	}
 
-	optionValue := func(name string) *string {
-		if !p.IsOptionSet(name) {
-			return nil
+	// HTTP client
+	//
+	// Command line options override the configuration for this execution
+	// only: they must not end up in the configuration file if it is
+	// rewritten by set-config.
+	httpConfig := config.API
+
+	stringOption := func(name string, ps *string) {
+		if p.IsOptionSet(name) {
+			*ps = p.OptionValue(name)
 		}
+	}
 
-		value := p.OptionValue(name)
-		return &value
+	stringOption("ca-file", &httpConfig.CAFile)
+	stringOption("client-cert", &httpConfig.ClientCert)
+	stringOption("client-key", &httpConfig.ClientKey)
+	stringOption("proxy", &httpConfig.Proxy)
+
+	if p.IsOptionSet("insecure-skip-verify") {
+		httpConfig.InsecureSkipVerify = true
+	}
+
+	httpClient, err := NewHTTPClient(&httpConfig)
+	if err != nil {
+		p.Fatal("cannot create http client: %v", err)
+	}
+
+	// Application
+	client, err := NewClient(config)
+	if err != nil {
+		p.Fatal("cannot create api client: %v", err)
 	}
 
-	app, err = NewApp(config, client)
+	app, err = NewApp(config, client, httpClient)
 	if err != nil {
 		p.Fatal("%v", err)
 	}
 
-	app.projectIdOption = optionValue("project-id")
-	app.projectNameOption = optionValue("project-name")
+	initializeAppOptions()
+}
 
-	name := p.CommandName()
+// initializeOptions applies command line options which do not depend on the
+// application. It is also used by the shell for each command.
+func initializeOptions(config *Config) {
+	skipConfirmations = p.IsOptionSet("yes")
+	traceHTTP = p.IsOptionSet("trace")
 
-	loadAPIKey := true
-	for _, cmdName := range noAPIKeyCommands() {
-		if name == cmdName {
-			loadAPIKey = false
-			break
+	// NO_COLOR (https://no-color.org) is also set for child processes
+	// when colors are disabled.
+	colorOutput = config.Interface.Color && !p.IsOptionSet("no-color") &&
+		os.Getenv("NO_COLOR") == ""
+}
+
+// initializeAppOptions applies command line options to the application. It is
+// also used by the shell for each command.
+func initializeAppOptions() {
+	optionValue := func(name string) *string {
+		if !p.IsOptionSet(name) {
+			return nil
 		}
-	}
 
-	if loadAPIKey {
-		app.LoadAPIKey()
+		value := p.OptionValue(name)
+		return &value
 	}
 
-	if !config.Misc.DisableUpdateCheck && name != "update" {
-		app.LookForLastBuild()
+	if p.IsOptionSet("project-id") || p.IsOptionSet("project-name") {
+		app.projectIdOption = optionValue("project-id")
+		app.projectNameOption = optionValue("project-name")
 	}
 
-	p.Run()
-}
+	if timezone := optionValue("timezone"); timezone != nil {
+		location, err := time.LoadLocation(*timezone)
+		if err != nil {
+			p.Fatal("invalid timezone %q: %v", *timezone, err)
+		}
 
-func noAPIKeyCommands() []string {
-	return []string{
-		"get-config",
-		"help",
-		"set-config",
-		"show-config",
-		"update",
-		"version",
+		app.Location = location
 	}
 }
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"time"
+)
+
+type EventFilter struct {
+	Connector string
+	Name      string
+	After     *time.Time
+	Before    *time.Time
+}
+
+func (f *EventFilter) Match(e *Event) bool {
+	if f.Connector != "" && e.Connector != f.Connector {
+		return false
+	}
+
+	if f.Name != "" && e.Name != f.Name {
+		return false
+	}
+
+	if f.After != nil && e.EventTime.Before(*f.After) {
+		return false
+	}
+
+	if f.Before != nil && !e.EventTime.Before(*f.Before) {
+		return false
+	}
+
+	return true
+}
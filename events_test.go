@@ -0,0 +1,56 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEventFilterMatch(t *testing.T) {
+	assert := assert.New(t)
+
+	date := func(s string) *time.Time {
+		t, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			panic(err)
+		}
+
+		return &t
+	}
+
+	event := Event{
+		Connector: "github",
+		Name:      "push",
+		EventTime: *date("2026-10-19T10:00:00Z"),
+	}
+
+	tests := []struct {
+		filter EventFilter
+		match  bool
+	}{
+		{EventFilter{}, true},
+		{EventFilter{Connector: "github"}, true},
+		{EventFilter{Connector: "generic"}, false},
+		{EventFilter{Name: "push"}, true},
+		{EventFilter{Name: "tag"}, false},
+		{EventFilter{Connector: "github", Name: "tag"}, false},
+
+		{EventFilter{After: date("2026-10-19T09:00:00Z")}, true},
+		{EventFilter{After: date("2026-10-19T10:00:00Z")}, true},
+		{EventFilter{After: date("2026-10-19T11:00:00Z")}, false},
+
+		{EventFilter{Before: date("2026-10-19T11:00:00Z")}, true},
+		{EventFilter{Before: date("2026-10-19T10:00:00Z")}, false},
+		{EventFilter{Before: date("2026-10-19T09:00:00Z")}, false},
+
+		{EventFilter{
+			After:  date("2026-10-19T09:00:00Z"),
+			Before: date("2026-10-19T11:00:00Z"),
+		}, true},
+	}
+
+	for i, test := range tests {
+		assert.Equal(test.match, test.filter.Match(&event), "test %d", i)
+	}
+}
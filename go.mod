@@ -7,6 +7,8 @@ require (
 	github.com/google/go-github/v40 v40.0.0
 	github.com/qri-io/jsonpointer v0.1.1
 	github.com/stretchr/testify v1.7.0
+	golang.org/x/sys v0.0.0-20210615035016-665e8c7367d1
+	golang.org/x/term v0.0.0-20220526004731-065cf7ba2467
 	gopkg.in/yaml.v3 v3.0.0-20210107192922-496545a6307b
 )
 
@@ -17,3 +19,8 @@ require (
 	github.com/pmezard/go-difflib v1.0.0 // indirect
 	golang.org/x/crypto v0.0.0-20210817164053-32db794688a5 // indirect
 )
+
+// go-program terminates the process with os.Exit; the local copy makes the
+// exit function replaceable so that the shell can execute commands in the
+// same process.
+replace github.com/exograd/go-program => ./third_party/go-program
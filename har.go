@@ -0,0 +1,275 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// See http://www.softwareishard.com/blog/har-12-spec/ for the specification
+// of the HAR format.
+
+type HARCreator struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+type HAREntry struct {
+	StartedDateTime time.Time   `json:"startedDateTime"`
+	Time            float64     `json:"time"`
+	Request         HARRequest  `json:"request"`
+	Response        HARResponse `json:"response"`
+	Cache           struct{}    `json:"cache"`
+	Timings         HARTimings  `json:"timings"`
+}
+
+type HARRequest struct {
+	Method      string         `json:"method"`
+	URL         string         `json:"url"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []HARNameValue `json:"cookies"`
+	Headers     []HARNameValue `json:"headers"`
+	QueryString []HARNameValue `json:"queryString"`
+	PostData    *HARPostData   `json:"postData,omitempty"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int            `json:"bodySize"`
+}
+
+type HARResponse struct {
+	Status      int            `json:"status"`
+	StatusText  string         `json:"statusText"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []HARNameValue `json:"cookies"`
+	Headers     []HARNameValue `json:"headers"`
+	Content     HARContent     `json:"content"`
+	RedirectURL string         `json:"redirectURL"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int            `json:"bodySize"`
+}
+
+type HARNameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type HARPostData struct {
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+	Comment  string `json:"comment,omitempty"`
+}
+
+type HARContent struct {
+	Size     int    `json:"size"`
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text,omitempty"`
+	Comment  string `json:"comment,omitempty"`
+}
+
+type HARTimings struct {
+	Send    float64 `json:"send"`
+	Wait    float64 `json:"wait"`
+	Receive float64 `json:"receive"`
+}
+
+// HARRecorder appends each entry to the file as soon as the request is done,
+// overwriting the end of the log, so that the archive is valid even if evcli
+// exits with a fatal error.
+type HARRecorder struct {
+	FilePath string
+
+	file      *os.File
+	offset    int64
+	nbEntries int
+	mutex     sync.Mutex
+}
+
+func NewHARRecorder(filePath string) *HARRecorder {
+	r := &HARRecorder{
+		FilePath: filePath,
+	}
+
+	return r
+}
+
+func (r *HARRecorder) Record(req *http.Request, reqBody *CapturedBody, res *http.Response, resBody *CapturedBody, start time.Time, d time.Duration) error {
+	ms := float64(d.Microseconds()) / 1000.0
+
+	entry := HAREntry{
+		StartedDateTime: start,
+		Time:            ms,
+		Request: HARRequest{
+			Method:      req.Method,
+			URL:         req.URL.String(),
+			HTTPVersion: req.Proto,
+			Cookies:     []HARNameValue{},
+			Headers:     harHeaders(req.Header),
+			QueryString: []HARNameValue{},
+			HeadersSize: -1,
+			BodySize:    reqBody.Size(req.ContentLength),
+		},
+		Timings: HARTimings{
+			Wait: ms,
+		},
+	}
+
+	for name, values := range req.URL.Query() {
+		for _, value := range values {
+			entry.Request.QueryString = append(entry.Request.QueryString,
+				HARNameValue{Name: name, Value: value})
+		}
+	}
+
+	if !reqBody.IsEmpty() {
+		entry.Request.PostData = &HARPostData{
+			MimeType: reqBody.ContentType,
+		}
+
+		entry.Request.PostData.Text, entry.Request.PostData.Comment =
+			harBodyText(reqBody)
+	}
+
+	if res != nil {
+		entry.Response = HARResponse{
+			Status:      res.StatusCode,
+			StatusText:  http.StatusText(res.StatusCode),
+			HTTPVersion: res.Proto,
+			Cookies:     []HARNameValue{},
+			Headers:     harHeaders(res.Header),
+			Content: HARContent{
+				Size:     resBody.Size(res.ContentLength),
+				MimeType: resBody.ContentType,
+			},
+			HeadersSize: -1,
+			BodySize:    resBody.Size(res.ContentLength),
+		}
+
+		entry.Response.Content.Text, entry.Response.Content.Comment =
+			harBodyText(resBody)
+	} else {
+		// The request failed without any response; HAR viewers use a 0
+		// status for this situation.
+		entry.Response = HARResponse{
+			Cookies:     []HARNameValue{},
+			Headers:     []HARNameValue{},
+			HeadersSize: -1,
+			BodySize:    -1,
+		}
+	}
+
+	data, err := json.MarshalIndent(entry, "    ", "  ")
+	if err != nil {
+		return fmt.Errorf("cannot encode har entry: %w", err)
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	return r.write(data)
+}
+
+func (r *HARRecorder) open() error {
+	file, err := os.OpenFile(r.FilePath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC,
+		0600)
+	if err != nil {
+		return err
+	}
+
+	creator := HARCreator{
+		Name:    "evcli",
+		Version: buildId,
+	}
+
+	creatorData, err := json.Marshal(creator)
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("cannot encode har creator: %w", err)
+	}
+
+	header := "{\n  \"log\": {\n    \"version\": \"1.2\",\n" +
+		"    \"creator\": " + string(creatorData) + ",\n" +
+		"    \"entries\": [\n"
+
+	if _, err := file.WriteString(header); err != nil {
+		file.Close()
+		return err
+	}
+
+	r.file = file
+	r.offset = int64(len(header))
+
+	return nil
+}
+
+// write adds an entry at the end of the log and rewrites the end of the
+// document after it.
+func (r *HARRecorder) write(entryData []byte) error {
+	if r.file == nil {
+		if err := r.open(); err != nil {
+			return fmt.Errorf("cannot open %s: %w", r.FilePath, err)
+		}
+	}
+
+	var buf bytes.Buffer
+
+	if r.nbEntries > 0 {
+		buf.WriteString(",\n")
+	}
+	buf.WriteString("    ")
+	buf.Write(entryData)
+
+	entrySize := int64(buf.Len())
+
+	buf.WriteString("\n    ]\n  }\n}\n")
+
+	if _, err := r.file.WriteAt(buf.Bytes(), r.offset); err != nil {
+		return fmt.Errorf("cannot write %s: %w", r.FilePath, err)
+	}
+
+	r.offset += entrySize
+	r.nbEntries++
+
+	return nil
+}
+
+// harBodyText returns the text of a body and a comment explaining why it was
+// not recorded entirely if necessary.
+func harBodyText(body *CapturedBody) (string, string) {
+	if body.IsEmpty() {
+		return "", ""
+	}
+
+	if !body.IsText() {
+		return "", "binary body not recorded"
+	}
+
+	if body.Truncated {
+		return body.Text(), fmt.Sprintf("body truncated to %d bytes",
+			len(body.Data))
+	}
+
+	return body.Text(), ""
+}
+
+func harHeaders(header http.Header) []HARNameValue {
+	header = RedactHeader(header)
+
+	names := make([]string, 0, len(header))
+	for name := range header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	pairs := []HARNameValue{}
+	for _, name := range names {
+		for _, value := range header[name] {
+			pairs = append(pairs, HARNameValue{Name: name, Value: value})
+		}
+	}
+
+	return pairs
+}
@@ -1,19 +1,104 @@
 package main
 
 import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
 	"fmt"
+	"io/ioutil"
 	"net/http"
+	"net/url"
+	"os"
+	"sort"
 	"strconv"
+	"strings"
 	"time"
 )
 
-func NewHTTPClient() *http.Client {
+func NewHTTPClient(config *APIConfig) (*http.Client, error) {
+	transport, err := NewHTTPTransport(config)
+	if err != nil {
+		return nil, err
+	}
+
 	c := &http.Client{
 		Timeout:   30 * time.Second,
-		Transport: NewRoundTripper(http.DefaultTransport),
+		Transport: NewRoundTripper(transport),
+	}
+
+	return c, nil
+}
+
+func NewHTTPTransport(config *APIConfig) (*http.Transport, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	if config.Proxy != "" {
+		// Parsing errors contain the URI, which may contain credentials
+		proxyURI, err := url.Parse(config.Proxy)
+		if err != nil {
+			var urlErr *url.Error
+			if errors.As(err, &urlErr) {
+				err = urlErr.Err
+			}
+
+			return nil, fmt.Errorf("invalid proxy uri %s: %w",
+				RedactURI(config.Proxy), err)
+		}
+
+		p.Debug(1, "using proxy %s", RedactURI(config.Proxy))
+
+		transport.Proxy = http.ProxyURL(proxyURI)
+	}
+
+	tlsConfig := &tls.Config{}
+
+	if config.CAFile != "" {
+		data, err := ioutil.ReadFile(config.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read ca file: %w", err)
+		}
+
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			p.Debug(1, "cannot load system certificate pool: %v", err)
+			pool = x509.NewCertPool()
+		}
+
+		if !pool.AppendCertsFromPEM(data) {
+			return nil, fmt.Errorf("no valid certificate found in %s",
+				config.CAFile)
+		}
+
+		tlsConfig.RootCAs = pool
+	}
+
+	if config.ClientCert != "" || config.ClientKey != "" {
+		if config.ClientCert == "" {
+			return nil, fmt.Errorf("missing client certificate")
+		} else if config.ClientKey == "" {
+			return nil, fmt.Errorf("missing client key")
+		}
+
+		cert, err := tls.LoadX509KeyPair(config.ClientCert, config.ClientKey)
+		if err != nil {
+			return nil, fmt.Errorf("cannot load client certificate: %w", err)
+		}
+
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	if config.InsecureSkipVerify {
+		fmt.Fprintln(os.Stderr, Colorize(ColorRed, "WARNING: TLS "+
+			"certificate verification is disabled, connections are not "+
+			"secure and API keys can be intercepted."))
+
+		tlsConfig.InsecureSkipVerify = true
 	}
 
-	return c
+	transport.TLSClientConfig = tlsConfig
+
+	return transport, nil
 }
 
 type RoundTripper struct {
@@ -29,8 +114,33 @@ func NewRoundTripper(rt http.RoundTripper) *RoundTripper {
 func (rt *RoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
 	req.Header.Add("User-Agent", app.UserAgent)
 
+	capture := traceHTTP || harRecorder != nil
+
+	var reqBody *CapturedBody
+	if capture {
+		var err error
+		reqBody, err = captureRequestBody(req)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read request body: %w", err)
+		}
+	}
+
+	if traceHTTP {
+		TraceRequest(req, reqBody)
+	}
+
 	start := time.Now()
 	res, err := rt.RoundTripper.RoundTrip(req)
+
+	var resBody *CapturedBody
+	if capture && err == nil {
+		resBody, err = captureResponseBody(res)
+		if err != nil {
+			resBody = &CapturedBody{}
+			err = fmt.Errorf("cannot read response body: %w", err)
+		}
+	}
+
 	d := time.Now().Sub(start)
 
 	var statusString string
@@ -42,9 +152,43 @@ func (rt *RoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
 
 	p.Debug(2, "%s %s %s %s", req.Method, req.URL.String(), statusString,
 		FormatRequestDuration(d))
+	p.Debug(3, "%s", FormatHeader(req.Header, "  "))
+
+	if traceHTTP && res != nil {
+		TraceResponse(res, resBody, d)
+	}
+
+	if harRecorder != nil {
+		if err := harRecorder.Record(req, reqBody, res, resBody, start,
+			d); err != nil {
+			p.Error("cannot record request: %v", err)
+		}
+	}
+
 	return res, err
 }
 
+// FormatHeader renders a header set with sorted names and sensitive values
+// redacted; it must be used every time headers are logged.
+func FormatHeader(header http.Header, indent string) string {
+	header = RedactHeader(header)
+
+	names := make([]string, 0, len(header))
+	for name := range header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var buf bytes.Buffer
+	for _, name := range names {
+		for _, value := range header[name] {
+			fmt.Fprintf(&buf, "%s%s: %s\n", indent, name, value)
+		}
+	}
+
+	return strings.TrimSuffix(buf.String(), "\n")
+}
+
 func FormatRequestDuration(d time.Duration) string {
 	s := d.Seconds()
 
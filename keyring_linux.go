@@ -0,0 +1,53 @@
+//go:build linux
+// +build linux
+
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// API keys are stored in the Secret Service (GNOME Keyring, KWallet, KeePassXC,
+// etc.) with the "service" attribute set to "evcli" and the "endpoint"
+// attribute set to the API endpoint. We rely on secret-tool, distributed with
+// libsecret, to talk to the Secret Service D-Bus API. It can also be used to
+// store the key:
+//
+//   secret-tool store --label=evcli service evcli endpoint <endpoint>
+
+func ReadKeyringAPIKey(endpoint string) (string, error) {
+	if _, err := exec.LookPath("secret-tool"); err != nil {
+		return "", fmt.Errorf("secret-tool not found, libsecret must " +
+			"be installed to use the secret service")
+	}
+
+	var stdout bytes.Buffer
+
+	cmd := exec.Command("secret-tool", "lookup",
+		"service", "evcli", "endpoint", endpoint)
+	cmd.Stdout = &stdout
+	cmd.Stderr = os.Stderr
+
+	if err := cmd.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && exitErr.ExitCode() == 1 {
+			return "", fmt.Errorf("no api key found in the secret service "+
+				"for endpoint %s", endpoint)
+		}
+
+		return "", fmt.Errorf("cannot run secret-tool: %w", err)
+	}
+
+	key := strings.TrimSpace(stdout.String())
+	if key == "" {
+		return "", fmt.Errorf("empty api key in the secret service for "+
+			"endpoint %s", endpoint)
+	}
+
+	return key, nil
+}
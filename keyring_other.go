@@ -0,0 +1,14 @@
+//go:build !linux
+// +build !linux
+
+package main
+
+import (
+	"fmt"
+	"runtime"
+)
+
+func ReadKeyringAPIKey(endpoint string) (string, error) {
+	return "", fmt.Errorf("keyring storage is not supported on %s",
+		runtime.GOOS)
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	PipelineStatusCreated    = "created"
+	PipelineStatusStarted    = "started"
+	PipelineStatusAborted    = "aborted"
+	PipelineStatusSuccessful = "successful"
+	PipelineStatusFailed     = "failed"
+)
+
+var PipelineStatuses = []string{
+	PipelineStatusCreated,
+	PipelineStatusStarted,
+	PipelineStatusAborted,
+	PipelineStatusSuccessful,
+	PipelineStatusFailed,
+}
+
+// PipelineActiveStatuses are the statuses of pipelines which have not
+// finished yet and can therefore be aborted.
+var PipelineActiveStatuses = []string{
+	PipelineStatusCreated,
+	PipelineStatusStarted,
+}
+
+type PipelineFilter struct {
+	Statuses  []string
+	Name      string
+	TriggerId string
+	EventId   string
+	After     *time.Time
+	Before    *time.Time
+
+	// The maximum number of pipelines to select, 0 meaning no limit.
+	Limit int
+}
+
+func (f *PipelineFilter) IsEmpty() bool {
+	return len(f.Statuses) == 0 && f.Name == "" && f.TriggerId == "" &&
+		f.EventId == "" && f.After == nil && f.Before == nil
+}
+
+func (f *PipelineFilter) Match(p *Pipeline, eventTime time.Time) bool {
+	if len(f.Statuses) > 0 {
+		found := false
+		for _, status := range f.Statuses {
+			if p.Status == status {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			return false
+		}
+	}
+
+	if f.Name != "" && p.Name != f.Name {
+		return false
+	}
+
+	if f.TriggerId != "" && p.TriggerId != f.TriggerId {
+		return false
+	}
+
+	if f.EventId != "" && p.EventId != f.EventId {
+		return false
+	}
+
+	if f.After != nil && eventTime.Before(*f.After) {
+		return false
+	}
+
+	if f.Before != nil && !eventTime.Before(*f.Before) {
+		return false
+	}
+
+	return true
+}
+
+func isPipelineStatus(s string) bool {
+	for _, status := range PipelineStatuses {
+		if s == status {
+			return true
+		}
+	}
+
+	return false
+}
+
+type PipelineColumn struct {
+	Name  string
+	Label string
+	Value func(*Pipeline) interface{}
+}
+
+var ExtraPipelineColumns = []PipelineColumn{
+	{"trigger", "trigger id",
+		func(p *Pipeline) interface{} { return p.TriggerId }},
+	{"event", "event id",
+		func(p *Pipeline) interface{} { return p.EventId }},
+	{"concurrency", "concurrent",
+		func(p *Pipeline) interface{} { return p.Concurrent }},
+	{"creation-time", "creation time",
+		func(p *Pipeline) interface{} { return p.CreationTime }},
+	{"end-time", "end time",
+		func(p *Pipeline) interface{} { return p.EndTime }},
+}
+
+func FindExtraPipelineColumn(name string) (*PipelineColumn, error) {
+	var names []string
+
+	for i, column := range ExtraPipelineColumns {
+		if column.Name == name {
+			return &ExtraPipelineColumns[i], nil
+		}
+
+		names = append(names, column.Name)
+	}
+
+	return nil, fmt.Errorf("unknown column %q (must be one of %s)",
+		name, strings.Join(names, ", "))
+}
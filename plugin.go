@@ -0,0 +1,97 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Plugins are executables named "evcli-<name>" available in one of the
+// directories listed in PATH. Running "evcli <name>" when <name> is not a
+// builtin command executes the plugin with the remaining arguments. As for
+// PATH lookups, the first plugin found with a given name is used.
+
+const pluginPrefix = "evcli-"
+
+type Plugin struct {
+	Name string
+	Path string
+}
+
+type Plugins []*Plugin
+
+func FindPlugin(name string) (*Plugin, error) {
+	filePath, err := exec.LookPath(pluginPrefix + name)
+	if err != nil {
+		return nil, err
+	}
+
+	plugin := Plugin{
+		Name: name,
+		Path: filePath,
+	}
+
+	return &plugin, nil
+}
+
+func FindPlugins() (Plugins, error) {
+	var plugins Plugins
+	names := make(map[string]struct{})
+
+	for _, dirPath := range filepath.SplitList(os.Getenv("PATH")) {
+		if dirPath == "" {
+			dirPath = "."
+		}
+
+		entries, err := ioutil.ReadDir(dirPath)
+		if err != nil {
+			if os.IsNotExist(err) || os.IsPermission(err) {
+				continue
+			}
+
+			return nil, fmt.Errorf("cannot read directory %s: %w", dirPath, err)
+		}
+
+		for _, entry := range entries {
+			fileName := entry.Name()
+			if !strings.HasPrefix(fileName, pluginPrefix) {
+				continue
+			}
+
+			name := strings.TrimPrefix(fileName, pluginPrefix)
+			if name == "" {
+				continue
+			}
+
+			if _, found := names[name]; found {
+				continue
+			}
+
+			filePath := filepath.Join(dirPath, fileName)
+
+			// Follow symlinks, plugins are often installed that way
+			info, err := os.Stat(filePath)
+			if err != nil || !info.Mode().IsRegular() ||
+				info.Mode()&0111 == 0 {
+				continue
+			}
+
+			names[name] = struct{}{}
+
+			plugins = append(plugins, &Plugin{
+				Name: name,
+				Path: filePath,
+			})
+		}
+	}
+
+	sort.Slice(plugins, func(i, j int) bool {
+		return plugins[i].Name < plugins[j].Name
+	})
+
+	return plugins, nil
+}
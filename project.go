@@ -2,11 +2,16 @@ package main
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
+	"os"
 	"path"
+	"path/filepath"
 )
 
+const projectFileName = "eventline-project.json"
+
 type ProjectFile struct {
 	Id   string `json:"id,omitempty"`
 	Name string `json:"name"`
@@ -29,7 +34,7 @@ func (pf *ProjectFile) UnmarshalJSON(data []byte) error {
 }
 
 func (pf *ProjectFile) Read(dirPath string) error {
-	filePath := path.Join(dirPath, "eventline-project.json")
+	filePath := path.Join(dirPath, projectFileName)
 
 	p.Debug(1, "reading project file %s", filePath)
 	data, err := ioutil.ReadFile(filePath)
@@ -50,9 +55,44 @@ func (pf *ProjectFile) Write(dirPath string) error {
 		return fmt.Errorf("cannot encode json data: %w", err)
 	}
 
-	filePath := path.Join(dirPath, "eventline-project.json")
+	filePath := path.Join(dirPath, projectFileName)
 
 	p.Debug(1, "writing project file %s", filePath)
 
 	return ioutil.WriteFile(filePath, data, 0644)
 }
+
+// FindProjectDirectory looks for a project file in a directory and in its
+// parents, stopping at the root of the filesystem or at the root of the git
+// repository containing the directory. It returns the absolute path of the
+// directory containing the project file, or an empty string if there is
+// none.
+func FindProjectDirectory(dirPath string) (string, error) {
+	dirPath, err := filepath.Abs(dirPath)
+	if err != nil {
+		return "", fmt.Errorf("cannot obtain absolute path: %w", err)
+	}
+
+	for {
+		filePath := filepath.Join(dirPath, projectFileName)
+
+		_, err := os.Stat(filePath)
+		if err == nil {
+			return dirPath, nil
+		} else if !errors.Is(err, os.ErrNotExist) {
+			return "", fmt.Errorf("cannot stat %s: %w", filePath, err)
+		}
+
+		// .git is a file in worktrees and submodules
+		if _, err := os.Stat(filepath.Join(dirPath, ".git")); err == nil {
+			return "", nil
+		}
+
+		parentPath := filepath.Dir(dirPath)
+		if parentPath == dirPath {
+			return "", nil
+		}
+
+		dirPath = parentPath
+	}
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFindProjectDirectory(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	rootPath := t.TempDir()
+
+	projectPath := filepath.Join(rootPath, "repo", "project")
+	subPath := filepath.Join(projectPath, "tasks", "deploy")
+
+	require.NoError(os.MkdirAll(subPath, 0700))
+	require.NoError(os.Mkdir(filepath.Join(rootPath, "repo", ".git"), 0700))
+
+	find := func(dirPath string) string {
+		dirPath, err := FindProjectDirectory(dirPath)
+		require.NoError(err)
+		return dirPath
+	}
+
+	assert.Equal("", find(subPath))
+
+	filePath := filepath.Join(projectPath, projectFileName)
+	require.NoError(os.WriteFile(filePath, []byte(`{"id":"x"}`), 0600))
+
+	assert.Equal(projectPath, find(subPath))
+	assert.Equal(projectPath, find(projectPath))
+
+	// The search stops at the root of the git repository
+	require.NoError(os.Rename(filePath, filepath.Join(rootPath, projectFileName)))
+	assert.Equal("", find(subPath))
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+const RedactedValue = "********"
+
+var sensitiveHeaders = []string{
+	"Authorization",
+	"Cookie",
+	"Proxy-Authorization",
+	"Set-Cookie",
+}
+
+// Members of JSON objects whose name contains one of these words have their
+// value masked in request and response bodies.
+var sensitiveFieldWords = []string{
+	"key",
+	"password",
+	"secret",
+	"token",
+}
+
+// The expression matches string members of JSON objects; it is used instead
+// of a decoder so that truncated bodies are redacted too.
+var jsonStringMemberRE = regexp.MustCompile(
+	`"((?:[^"\\]|\\.)*)"(\s*:\s*)"(?:[^"\\]|\\.)*"`)
+
+func IsSensitiveHeader(name string) bool {
+	name = http.CanonicalHeaderKey(name)
+
+	for _, name2 := range sensitiveHeaders {
+		if name == name2 {
+			return true
+		}
+	}
+
+	return false
+}
+
+// RedactHeader returns a copy of a header set where the values of sensitive
+// headers are masked. The authentication scheme of authorization headers is
+// kept since it is useful for debugging and does not contain any secret.
+func RedactHeader(header http.Header) http.Header {
+	header2 := header.Clone()
+
+	for name, values := range header2 {
+		if !IsSensitiveHeader(name) {
+			continue
+		}
+
+		for i, value := range values {
+			values[i] = redactHeaderValue(name, value)
+		}
+	}
+
+	return header2
+}
+
+func redactHeaderValue(name, value string) string {
+	if strings.HasSuffix(name, "Authorization") {
+		if i := strings.IndexByte(value, ' '); i >= 0 {
+			return value[:i+1] + RedactedValue
+		}
+	}
+
+	return RedactedValue
+}
+
+// RedactURI masks the user information of a URI, e.g. credentials embedded
+// in a proxy URI. URIs which cannot be parsed are entirely masked if they
+// may contain user information.
+func RedactURI(s string) string {
+	uri, err := url.Parse(s)
+	if err != nil {
+		if strings.Contains(s, "@") {
+			return RedactedValue
+		}
+
+		return s
+	}
+
+	if uri.User == nil {
+		return s
+	}
+
+	// Setting the user information to the redacted value would percent
+	// encode it.
+	uri.User = nil
+	prefix := uri.Scheme + "://"
+
+	return prefix + RedactedValue + "@" + strings.TrimPrefix(uri.String(), prefix)
+}
+
+func IsSensitiveField(name string) bool {
+	name = strings.ToLower(name)
+
+	for _, word := range sensitiveFieldWords {
+		if strings.Contains(name, word) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// RedactBody masks the string values of sensitive members in JSON bodies.
+// Other bodies, e.g. scratchpad values, are returned unmodified.
+func RedactBody(body []byte, contentType string) []byte {
+	if !strings.Contains(strings.ToLower(contentType), "json") {
+		return body
+	}
+
+	return jsonStringMemberRE.ReplaceAllFunc(body, func(m []byte) []byte {
+		groups := jsonStringMemberRE.FindSubmatch(m)
+		if !IsSensitiveField(string(groups[1])) {
+			return m
+		}
+
+		return []byte(`"` + string(groups[1]) + `"` + string(groups[2]) +
+			`"` + RedactedValue + `"`)
+	})
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"sort"
+
+	"github.com/exograd/go-program"
+)
+
+// go-program does not expose the commands and options of a program, while
+// evcli needs them to expand aliases, detect plugins and complete command
+// lines. Commands and options are therefore declared with a registry which
+// adds them to the program and records them in a specification.
+
+type Registry struct {
+	Program *program.Program
+	Spec    *CompletionSpec
+}
+
+type Command struct {
+	*program.Command
+
+	spec *CompletionCommand
+}
+
+func NewRegistry(prog *program.Program) *Registry {
+	r := Registry{
+		Program: prog,
+		Spec: &CompletionSpec{
+			Commands: make(map[string]*CompletionCommand),
+		},
+	}
+
+	// Options added by go-program when the program is created
+	r.Spec.Options = addCompletionOption(r.Spec.Options,
+		"h", "help", "", "print help and exit")
+	r.Spec.Options = addCompletionOption(r.Spec.Options,
+		"q", "quiet", "", "do not print status and information messages")
+	r.Spec.Options = addCompletionOption(r.Spec.Options,
+		"", "debug", "level", "print debug messages")
+
+	// Command added by go-program when the command line is parsed
+	r.Spec.Commands["help"] = &CompletionCommand{
+		Name:        "help",
+		Description: "print help and exit",
+		Arguments: []*CompletionArgument{
+			{Name: "command", Trailing: true},
+		},
+	}
+
+	return &r
+}
+
+func (r *Registry) AddFlag(shortName, longName, description string) {
+	r.Program.AddFlag(shortName, longName, description)
+
+	r.Spec.Options = addCompletionOption(r.Spec.Options,
+		shortName, longName, "", description)
+}
+
+func (r *Registry) AddOption(shortName, longName, valueName, defaultValue, description string) {
+	r.Program.AddOption(shortName, longName, valueName, defaultValue,
+		description)
+
+	r.Spec.Options = addCompletionOption(r.Spec.Options,
+		shortName, longName, valueName, description)
+}
+
+func (r *Registry) AddCommand(name, description string, main program.Main) *Command {
+	c := Command{
+		Command: r.Program.AddCommand(name, description, main),
+
+		spec: &CompletionCommand{
+			Name:        name,
+			Description: description,
+		},
+	}
+
+	r.Spec.Commands[name] = c.spec
+
+	return &c
+}
+
+func (c *Command) AddFlag(shortName, longName, description string) {
+	c.Command.AddFlag(shortName, longName, description)
+
+	c.spec.Options = addCompletionOption(c.spec.Options,
+		shortName, longName, "", description)
+}
+
+func (c *Command) AddOption(shortName, longName, valueName, defaultValue, description string) {
+	c.Command.AddOption(shortName, longName, valueName, defaultValue,
+		description)
+
+	c.spec.Options = addCompletionOption(c.spec.Options,
+		shortName, longName, valueName, description)
+}
+
+func (c *Command) AddArgument(name, description string) {
+	c.Command.AddArgument(name, description)
+
+	c.spec.Arguments = append(c.spec.Arguments,
+		&CompletionArgument{Name: name})
+}
+
+func (c *Command) AddOptionalArgument(name, description string) {
+	c.Command.AddOptionalArgument(name, description)
+
+	c.spec.Arguments = append(c.spec.Arguments,
+		&CompletionArgument{Name: name})
+}
+
+func (c *Command) AddTrailingArgument(name, description string) {
+	c.Command.AddTrailingArgument(name, description)
+
+	c.spec.Arguments = append(c.spec.Arguments,
+		&CompletionArgument{Name: name, Trailing: true})
+}
+
+func addCompletionOption(options []*CompletionOption, shortName, longName, valueName, description string) []*CompletionOption {
+	options = append(options, &CompletionOption{
+		ShortName:   shortName,
+		LongName:    longName,
+		ValueName:   valueName,
+		Description: description,
+	})
+
+	sort.Slice(options, func(i, j int) bool {
+		return options[i].LongName < options[j].LongName
+	})
+
+	return options
+}
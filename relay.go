@@ -0,0 +1,321 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Relayed requests are exposed to field mappings as a JSON object containing
+// request headers (with lowercase names) and the decoded request body, e.g.:
+//
+//   {"headers": {"x-github-event": "push"}, "body": {...}}
+//
+// Mapping expressions use a small subset of the jq syntax to extract values
+// from this document: ".body.repository.name", ".headers[\"x-request-id\"]",
+// ".body.commits[0].id".
+
+const maxRelayedBodySize = 10 * 1024 * 1024
+
+type EventRelay struct {
+	Connector string
+	EventName string
+	Mappings  FieldMappings
+}
+
+type FieldMapping struct {
+	Name       string
+	Expression string
+	Path       FieldPath
+}
+
+type FieldMappings []*FieldMapping
+
+type FieldPath []interface{}
+
+func ParseFieldMapping(s string) (*FieldMapping, error) {
+	parts := strings.SplitN(s, "=", 2)
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("invalid mapping format %q", s)
+	}
+
+	name := parts[0]
+	if name == "" {
+		return nil, fmt.Errorf("empty field name")
+	}
+
+	path, err := ParseFieldPath(parts[1])
+	if err != nil {
+		return nil, fmt.Errorf("invalid expression %q: %w", parts[1], err)
+	}
+
+	mapping := FieldMapping{
+		Name:       name,
+		Expression: parts[1],
+		Path:       path,
+	}
+
+	return &mapping, nil
+}
+
+func ParseFieldPath(s string) (FieldPath, error) {
+	if s == "" || s[0] != '.' {
+		return nil, fmt.Errorf("expression must start with '.'")
+	}
+
+	path := FieldPath{}
+
+	if s == "." {
+		return path, nil
+	}
+
+	for len(s) > 0 {
+		switch {
+		case s[0] == '.' && len(s) > 1 && s[1] == '[':
+			s = s[1:]
+
+		case s[0] == '.':
+			s = s[1:]
+
+			end := strings.IndexAny(s, ".[")
+			if end == -1 {
+				end = len(s)
+			}
+
+			key := s[:end]
+			if key == "" {
+				return nil, fmt.Errorf("empty key")
+			}
+
+			path = append(path, key)
+			s = s[end:]
+
+		case s[0] == '[':
+			// Quoted keys can contain ']' characters: look for the end of
+			// the string first.
+			start := 1
+			if len(s) > 1 && s[1] == '"' {
+				start = quotedStringEnd(s[1:]) + 1
+				if start == 0 {
+					return nil, fmt.Errorf("unterminated key %s", s[1:])
+				}
+			}
+
+			end := strings.IndexByte(s[start:], ']')
+			if end == -1 {
+				return nil, fmt.Errorf("missing ']'")
+			}
+			end += start
+
+			content := s[1:end]
+
+			if len(content) > 0 && content[0] == '"' {
+				key, err := strconv.Unquote(content)
+				if err != nil {
+					return nil, fmt.Errorf("invalid key %s", content)
+				}
+
+				path = append(path, key)
+			} else {
+				i, err := strconv.Atoi(content)
+				if err != nil || i < 0 {
+					return nil, fmt.Errorf("invalid index %q", content)
+				}
+
+				path = append(path, i)
+			}
+
+			s = s[end+1:]
+
+		default:
+			return nil, fmt.Errorf("unexpected character %q", s[0])
+		}
+	}
+
+	return path, nil
+}
+
+// quotedStringEnd returns the position following the closing quote of the
+// double-quoted string at the beginning of s, or -1 if the string is not
+// terminated.
+func quotedStringEnd(s string) int {
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '"':
+			return i + 1
+		}
+	}
+
+	return -1
+}
+
+// Eval follows jq semantics: accessing a missing key or an out of bound index
+// yields null instead of an error.
+func (path FieldPath) Eval(value interface{}) (interface{}, error) {
+	for _, elt := range path {
+		if value == nil {
+			return nil, nil
+		}
+
+		switch key := elt.(type) {
+		case string:
+			object, ok := value.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("cannot index %s with %q",
+					jsonTypeName(value), key)
+			}
+
+			value = object[key]
+
+		case int:
+			array, ok := value.([]interface{})
+			if !ok {
+				return nil, fmt.Errorf("cannot index %s with %d",
+					jsonTypeName(value), key)
+			}
+
+			if key >= len(array) {
+				value = nil
+			} else {
+				value = array[key]
+			}
+		}
+	}
+
+	return value, nil
+}
+
+func (ms FieldMappings) Apply(value interface{}) (map[string]interface{}, error) {
+	data := make(map[string]interface{})
+
+	for _, m := range ms {
+		fieldValue, err := m.Path.Eval(value)
+		if err != nil {
+			return nil, fmt.Errorf("cannot evaluate %q: %w", m.Expression, err)
+		}
+
+		data[m.Name] = fieldValue
+	}
+
+	return data, nil
+}
+
+func jsonTypeName(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	default:
+		return fmt.Sprintf("%T", value)
+	}
+}
+
+func (r *EventRelay) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != "POST" {
+		w.Header().Set("Allow", "POST")
+		r.replyError(w, 405, "method not allowed")
+		return
+	}
+
+	newEvent, err := r.newEvent(w, req)
+	if err != nil {
+		p.Error("cannot process request from %s: %v", req.RemoteAddr, err)
+		r.replyError(w, 400, err.Error())
+		return
+	}
+
+	events, err := app.Client.CreateEvent(newEvent)
+	if err != nil {
+		p.Error("cannot create event: %v", err)
+		r.replyError(w, 502, fmt.Sprintf("cannot create event: %v", err))
+		return
+	}
+
+	for _, event := range events {
+		p.Info("event %s created", event.Id)
+	}
+
+	r.reply(w, 200, events)
+}
+
+func (r *EventRelay) newEvent(w http.ResponseWriter, req *http.Request) (*NewEvent, error) {
+	body, err := ioutil.ReadAll(http.MaxBytesReader(w, req.Body,
+		maxRelayedBodySize))
+	if err != nil {
+		return nil, fmt.Errorf("cannot read request body: %w", err)
+	}
+
+	var bodyValue interface{}
+	if err := json.Unmarshal(body, &bodyValue); err != nil {
+		return nil, fmt.Errorf("invalid json body: %w", err)
+	}
+
+	var data []byte
+
+	if len(r.Mappings) == 0 {
+		data = body
+	} else {
+		headers := make(map[string]interface{})
+		for name := range req.Header {
+			headers[strings.ToLower(name)] = req.Header.Get(name)
+		}
+
+		document := map[string]interface{}{
+			"headers": headers,
+			"body":    bodyValue,
+		}
+
+		fields, err := r.Mappings.Apply(document)
+		if err != nil {
+			return nil, err
+		}
+
+		data, err = json.Marshal(fields)
+		if err != nil {
+			return nil, fmt.Errorf("cannot encode event data: %w", err)
+		}
+	}
+
+	eventTime := time.Now().UTC()
+
+	newEvent := NewEvent{
+		EventTime: &eventTime,
+		Connector: r.Connector,
+		Name:      r.EventName,
+		Data:      data,
+	}
+
+	return &newEvent, nil
+}
+
+func (r *EventRelay) reply(w http.ResponseWriter, status int, value interface{}) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		p.Error("cannot encode response: %v", err)
+		w.WriteHeader(500)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(data)
+}
+
+func (r *EventRelay) replyError(w http.ResponseWriter, status int, message string) {
+	r.reply(w, status, map[string]string{"error": message})
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseFieldPath(t *testing.T) {
+	assert := assert.New(t)
+
+	path, err := ParseFieldPath(".")
+	if assert.NoError(err) {
+		assert.Equal(FieldPath{}, path)
+	}
+
+	path, err = ParseFieldPath(".a.b")
+	if assert.NoError(err) {
+		assert.Equal(FieldPath{"a", "b"}, path)
+	}
+
+	path, err = ParseFieldPath(".a[2].b")
+	if assert.NoError(err) {
+		assert.Equal(FieldPath{"a", 2, "b"}, path)
+	}
+
+	path, err = ParseFieldPath(".headers[\"x-foo\"]")
+	if assert.NoError(err) {
+		assert.Equal(FieldPath{"headers", "x-foo"}, path)
+	}
+
+	path, err = ParseFieldPath(`.a["b]c"]["d\"]"][1]`)
+	if assert.NoError(err) {
+		assert.Equal(FieldPath{"a", "b]c", `d"]`, 1}, path)
+	}
+
+	path, err = ParseFieldPath(".a.[0]")
+	if assert.NoError(err) {
+		assert.Equal(FieldPath{"a", 0}, path)
+	}
+
+	_, err = ParseFieldPath("a")
+	assert.Error(err)
+
+	_, err = ParseFieldPath(".a..b")
+	assert.Error(err)
+
+	_, err = ParseFieldPath(".a[0")
+	assert.Error(err)
+
+	_, err = ParseFieldPath(".a[-1]")
+	assert.Error(err)
+
+	_, err = ParseFieldPath(`.a["b]`)
+	assert.Error(err)
+}
+
+func TestFieldPathEval(t *testing.T) {
+	assert := assert.New(t)
+
+	var value interface{}
+	err := json.Unmarshal([]byte(`{"a": {"b": [1, "x", {"c": true}]}}`),
+		&value)
+	require.NoError(t, err)
+
+	eval := func(s string) (interface{}, error) {
+		path, err := ParseFieldPath(s)
+		require.NoError(t, err)
+
+		return path.Eval(value)
+	}
+
+	v, err := eval(".a.b[1]")
+	if assert.NoError(err) {
+		assert.Equal("x", v)
+	}
+
+	v, err = eval(".a.b[2].c")
+	if assert.NoError(err) {
+		assert.Equal(true, v)
+	}
+
+	v, err = eval(".a.b[5]")
+	if assert.NoError(err) {
+		assert.Nil(v)
+	}
+
+	v, err = eval(".x.y.z")
+	if assert.NoError(err) {
+		assert.Nil(v)
+	}
+
+	_, err = eval(".a.b.c")
+	assert.Error(err)
+
+	_, err = eval(".a[0]")
+	assert.Error(err)
+}
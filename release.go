@@ -0,0 +1,334 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/exograd/go-program"
+)
+
+// Each release contains a checksum file listing the SHA-256 hash of all
+// binaries. If evcli was built with a release public key (see GNUmakefile),
+// the checksum file must also be signed with the associated Ed25519 private
+// key; the detached signature is published as a separate asset.
+//
+// Alternatively, builds can be distributed by a mirror (see the
+// misc.update_url configuration entry) publishing a JSON index, e.g.:
+//
+//   {
+//     "builds": [
+//       {
+//         "id": "v1.2.0",
+//         "files": [
+//           {
+//             "name": "evcli-linux-amd64",
+//             "uri": "v1.2.0/evcli-linux-amd64",
+//             "sha256": "2c26b46b68ffc68ff99b453c1d30413413422d706483bfa0f98a5e886266e7ae"
+//           }
+//         ]
+//       }
+//     ]
+//   }
+//
+// File URIs are relative to the URI of the index. If evcli was built with a
+// release public key, the detached signature of the index must be published
+// next to it with a ".sig" suffix.
+
+const checksumsAssetName = "evcli-checksums.txt"
+
+// Base64-encoded Ed25519 public key, set at build time.
+var releasePublicKey string
+
+type Build struct {
+	Id           *program.BuildId
+	AssetName    string
+	URI          string
+	ChecksumsURI string
+	SignatureURI string
+	Checksum     string
+}
+
+type UpdateIndex struct {
+	URI    *url.URL           `json:"-"`
+	Builds []UpdateIndexBuild `json:"builds"`
+}
+
+type UpdateIndexBuild struct {
+	Id    string            `json:"id"`
+	Files []UpdateIndexFile `json:"files"`
+}
+
+type UpdateIndexFile struct {
+	Name   string `json:"name"`
+	URI    string `json:"uri"`
+	SHA256 string `json:"sha256"`
+}
+
+func FetchUpdateIndex(ctx context.Context, uri string) (*UpdateIndex, error) {
+	indexURI, err := url.Parse(uri)
+	if err != nil {
+		return nil, fmt.Errorf("invalid uri %q: %w", uri, err)
+	}
+
+	data, err := fetch(ctx, uri)
+	if err != nil {
+		return nil, fmt.Errorf("cannot fetch update index: %w", err)
+	}
+
+	if releasePublicKey == "" {
+		p.Debug(1, "no release public key available, skipping signature "+
+			"verification")
+	} else {
+		signature, err := fetch(ctx, uri+".sig")
+		if err != nil {
+			return nil, fmt.Errorf("cannot fetch update index signature: %w",
+				err)
+		}
+
+		err = VerifyReleaseSignature(releasePublicKey, data, signature)
+		if err != nil {
+			return nil, err
+		}
+
+		p.Debug(1, "update index signature verified")
+	}
+
+	return DecodeUpdateIndex(data, indexURI)
+}
+
+func DecodeUpdateIndex(data []byte, indexURI *url.URL) (*UpdateIndex, error) {
+	var index UpdateIndex
+	if err := json.Unmarshal(data, &index); err != nil {
+		return nil, fmt.Errorf("cannot decode update index: %w", err)
+	}
+
+	for _, build := range index.Builds {
+		var id program.BuildId
+		if err := id.Parse(build.Id); err != nil {
+			return nil, fmt.Errorf("invalid build id %q: %w", build.Id, err)
+		}
+
+		for _, file := range build.Files {
+			if file.Name == "" {
+				return nil, fmt.Errorf("missing file name in build %s",
+					build.Id)
+			}
+
+			if _, err := url.Parse(file.URI); err != nil || file.URI == "" {
+				return nil, fmt.Errorf("invalid uri %q for file %s in "+
+					"build %s", file.URI, file.Name, build.Id)
+			}
+
+			if !isSHA256Hash(strings.ToLower(file.SHA256)) {
+				return nil, fmt.Errorf("invalid sha256 hash %q for file %s "+
+					"in build %s", file.SHA256, file.Name, build.Id)
+			}
+		}
+	}
+
+	index.URI = indexURI
+
+	return &index, nil
+}
+
+func (index *UpdateIndex) LastBuildId() (*program.BuildId, error) {
+	if len(index.Builds) == 0 {
+		return nil, fmt.Errorf("update index does not contain any build")
+	}
+
+	var lastId *program.BuildId
+
+	for _, build := range index.Builds {
+		var id program.BuildId
+		if err := id.Parse(build.Id); err != nil {
+			return nil, fmt.Errorf("invalid build id %q: %w", build.Id, err)
+		}
+
+		if lastId == nil || lastId.LowerThanOrEqualTo(id) {
+			lastId = &id
+		}
+	}
+
+	return lastId, nil
+}
+
+func (index *UpdateIndex) FindBuild(id *program.BuildId, osName, archName string) (*Build, error) {
+	var indexBuild *UpdateIndexBuild
+
+	for i, build := range index.Builds {
+		var buildId program.BuildId
+		if err := buildId.Parse(build.Id); err != nil {
+			return nil, fmt.Errorf("invalid build id %q: %w", build.Id, err)
+		}
+
+		if buildId.EqualTo(*id) {
+			indexBuild = &index.Builds[i]
+			break
+		}
+	}
+
+	if indexBuild == nil {
+		return nil, fmt.Errorf("build %v not found in update index", id)
+	}
+
+	build := Build{
+		Id:        id,
+		AssetName: "evcli-" + osName + "-" + archName,
+	}
+
+	for _, file := range indexBuild.Files {
+		if file.Name != build.AssetName {
+			continue
+		}
+
+		fileURI, err := url.Parse(file.URI)
+		if err != nil {
+			return nil, fmt.Errorf("invalid uri %q: %w", file.URI, err)
+		}
+
+		if !isSHA256Hash(strings.ToLower(file.SHA256)) {
+			return nil, fmt.Errorf("invalid sha256 hash %q for %s",
+				file.SHA256, file.Name)
+		}
+
+		build.URI = index.URI.ResolveReference(fileURI).String()
+		build.Checksum = strings.ToLower(file.SHA256)
+
+		return &build, nil
+	}
+
+	return nil, fmt.Errorf("no build available for os %s and arch %s",
+		osName, archName)
+}
+
+func fetchBuildChecksum(ctx context.Context, build *Build) (string, error) {
+	if build.Checksum != "" {
+		// Mirror builds are listed with their checksum in the update index
+		return build.Checksum, nil
+	}
+
+	checksums, err := fetch(ctx, build.ChecksumsURI)
+	if err != nil {
+		return "", fmt.Errorf("cannot fetch checksums: %w", err)
+	}
+
+	if releasePublicKey == "" {
+		p.Debug(1, "no release public key available, skipping signature "+
+			"verification")
+	} else {
+		if build.SignatureURI == "" {
+			return "", fmt.Errorf("missing checksum signature")
+		}
+
+		signature, err := fetch(ctx, build.SignatureURI)
+		if err != nil {
+			return "", fmt.Errorf("cannot fetch checksum signature: %w", err)
+		}
+
+		err = VerifyReleaseSignature(releasePublicKey, checksums,
+			signature)
+		if err != nil {
+			return "", err
+		}
+
+		p.Debug(1, "checksum signature verified")
+	}
+
+	table, err := ParseChecksums(checksums)
+	if err != nil {
+		return "", fmt.Errorf("invalid checksums: %w", err)
+	}
+
+	checksum, found := table[build.AssetName]
+	if !found {
+		return "", fmt.Errorf("no checksum found for %s", build.AssetName)
+	}
+
+	return checksum, nil
+}
+
+func VerifyReleaseSignature(publicKey string, data, signature []byte) error {
+	key, err := base64.StdEncoding.DecodeString(publicKey)
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		return fmt.Errorf("invalid release public key")
+	}
+
+	if !ed25519.Verify(ed25519.PublicKey(key), data, signature) {
+		return fmt.Errorf("invalid checksum signature")
+	}
+
+	return nil
+}
+
+func ParseChecksums(data []byte) (map[string]string, error) {
+	table := make(map[string]string)
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("invalid line %q", line)
+		}
+
+		hash := strings.ToLower(fields[0])
+		if !isSHA256Hash(hash) {
+			return nil, fmt.Errorf("invalid sha256 hash %q", hash)
+		}
+
+		table[strings.TrimPrefix(fields[1], "*")] = hash
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return table, nil
+}
+
+func isSHA256Hash(s string) bool {
+	data, err := hex.DecodeString(s)
+	return err == nil && len(data) == sha256.Size
+}
+
+func fetch(ctx context.Context, uri string) ([]byte, error) {
+	p.Debug(2, "fetching %s", uri)
+
+	req, err := http.NewRequestWithContext(ctx, "GET", uri, nil)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create http request: %w", err)
+	}
+
+	res, err := app.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("cannot send http request: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != 200 {
+		return nil, fmt.Errorf("request failed with status %d",
+			res.StatusCode)
+	}
+
+	data, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read response body: %w", err)
+	}
+
+	return data, nil
+}
@@ -0,0 +1,249 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/exograd/go-program"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testHash1 = "2c26b46b68ffc68ff99b453c1d30413413422d706483bfa0f98a5e886266e7ae"
+	testHash2 = "fcde2b2edba56bf408601fb721fe9b5c338d10ee429ea04fae5511b68fbf8fb9"
+)
+
+func TestParseChecksums(t *testing.T) {
+	assert := assert.New(t)
+
+	tests := []struct {
+		data      string
+		checksums map[string]string
+	}{
+		{"", map[string]string{}},
+		{testHash1 + "  evcli-linux-amd64\n",
+			map[string]string{"evcli-linux-amd64": testHash1}},
+		{testHash1 + " *evcli-linux-amd64\n\n" +
+			strings.ToUpper(testHash2) + "  evcli-darwin-arm64\n",
+			map[string]string{
+				"evcli-linux-amd64":  testHash1,
+				"evcli-darwin-arm64": testHash2,
+			}},
+
+		// Errors
+		{testHash1[:63] + "  evcli-linux-amd64\n", nil},
+		{testHash1 + "0  evcli-linux-amd64\n", nil},
+		{strings.Repeat("z", 64) + "  evcli-linux-amd64\n", nil},
+		{testHash1 + "\n", nil},
+		{testHash1 + "  evcli linux\n", nil},
+	}
+
+	for _, test := range tests {
+		checksums, err := ParseChecksums([]byte(test.data))
+
+		if test.checksums == nil {
+			assert.Error(err, test.data)
+		} else if assert.NoError(err, test.data) {
+			assert.Equal(test.checksums, checksums, test.data)
+		}
+	}
+}
+
+func TestVerifyReleaseSignature(t *testing.T) {
+	assert := assert.New(t)
+
+	publicKey, privateKey := generateTestReleaseKey(t)
+
+	data := []byte(testHash1 + "  evcli-linux-amd64\n")
+	signature := ed25519.Sign(privateKey, data)
+
+	otherPublicKey, _ := generateTestReleaseKey(t)
+
+	tests := []struct {
+		key       string
+		data      []byte
+		signature []byte
+		valid     bool
+	}{
+		{publicKey, data, signature, true},
+		{publicKey, []byte(testHash2 + "  evcli-linux-amd64\n"), signature,
+			false},
+		{publicKey, data, signature[:32], false},
+		{publicKey, data, nil, false},
+		{otherPublicKey, data, signature, false},
+		{"", data, signature, false},
+		{"not base64", data, signature, false},
+		{base64.StdEncoding.EncodeToString([]byte("short")), data,
+			signature, false},
+	}
+
+	for i, test := range tests {
+		err := VerifyReleaseSignature(test.key, test.data, test.signature)
+		if test.valid {
+			assert.NoError(err, "test %d", i)
+		} else {
+			assert.Error(err, "test %d", i)
+		}
+	}
+}
+
+func TestDecodeUpdateIndex(t *testing.T) {
+	assert := assert.New(t)
+
+	indexURI, _ := url.Parse("https://example.com/evcli/index.json")
+
+	file := func(name, uri, hash string) string {
+		return `{"name": "` + name + `", "uri": "` + uri + `", ` +
+			`"sha256": "` + hash + `"}`
+	}
+
+	build := func(id string, files ...string) string {
+		return `{"id": "` + id + `", "files": [` +
+			strings.Join(files, ", ") + `]}`
+	}
+
+	index := func(builds ...string) string {
+		return `{"builds": [` + strings.Join(builds, ", ") + `]}`
+	}
+
+	tests := []struct {
+		data  string
+		valid bool
+	}{
+		{index(), true},
+		{index(build("v1.2.0")), true},
+		{index(build("v1.2.0",
+			file("evcli-linux-amd64", "v1.2.0/evcli-linux-amd64", testHash1),
+			file("evcli-darwin-arm64", "v1.2.0/evcli-darwin-arm64",
+				strings.ToUpper(testHash2)))), true},
+
+		{`{"builds": {}}`, false},
+		{`[`, false},
+		{index(build("1.2")), false},
+		{index(build("")), false},
+		{index(build("v1.2.0",
+			file("evcli-linux-amd64", "evcli", testHash1[:63]))), false},
+		{index(build("v1.2.0",
+			file("evcli-linux-amd64", "evcli", strings.Repeat("g", 64)))),
+			false},
+		{index(build("v1.2.0", file("evcli-linux-amd64", "", testHash1))),
+			false},
+		{index(build("v1.2.0", file("evcli-linux-amd64", ":", testHash1))),
+			false},
+		{index(build("v1.2.0", file("", "evcli", testHash1))), false},
+	}
+
+	for _, test := range tests {
+		index, err := DecodeUpdateIndex([]byte(test.data), indexURI)
+		if test.valid {
+			if assert.NoError(err, test.data) {
+				assert.Equal(indexURI, index.URI)
+			}
+		} else {
+			assert.Error(err, test.data)
+		}
+	}
+}
+
+func TestUpdateIndex(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	indexURI, _ := url.Parse("https://example.com/evcli/index.json")
+
+	data := `{
+  "builds": [
+    {
+      "id": "v1.2.0",
+      "files": [
+        {"name": "evcli-linux-amd64", "uri": "v1.2.0/evcli-linux-amd64",
+         "sha256": "` + testHash1 + `"},
+        {"name": "evcli-darwin-arm64",
+         "uri": "/mirror/v1.2.0/evcli-darwin-arm64",
+         "sha256": "` + strings.ToUpper(testHash2) + `"}
+      ]
+    },
+    {
+      "id": "v1.10.0",
+      "files": [
+        {"name": "evcli-linux-amd64",
+         "uri": "https://cdn.example.com/evcli-linux-amd64",
+         "sha256": "` + testHash2 + `"}
+      ]
+    },
+    {
+      "id": "v1.3.0",
+      "files": []
+    }
+  ]
+}`
+
+	index, err := DecodeUpdateIndex([]byte(data), indexURI)
+	require.NoError(err)
+
+	lastId, err := index.LastBuildId()
+	require.NoError(err)
+	assert.Equal("v1.10.0", lastId.String())
+
+	buildId := func(s string) *program.BuildId {
+		var id program.BuildId
+		require.NoError(id.Parse(s))
+		return &id
+	}
+
+	tests := []struct {
+		id    string
+		os    string
+		arch  string
+		uri   string
+		hash  string
+		valid bool
+	}{
+		{"v1.2.0", "linux", "amd64",
+			"https://example.com/evcli/v1.2.0/evcli-linux-amd64", testHash1,
+			true},
+		{"v1.2.0", "darwin", "arm64",
+			"https://example.com/mirror/v1.2.0/evcli-darwin-arm64", testHash2,
+			true},
+		{"v1.10.0", "linux", "amd64",
+			"https://cdn.example.com/evcli-linux-amd64", testHash2, true},
+		{"v1.10.0", "darwin", "arm64", "", "", false},
+		{"v1.3.0", "linux", "amd64", "", "", false},
+		{"v1.4.0", "linux", "amd64", "", "", false},
+	}
+
+	for _, test := range tests {
+		label := test.id + "/" + test.os + "/" + test.arch
+
+		build, err := index.FindBuild(buildId(test.id), test.os, test.arch)
+		if !test.valid {
+			assert.Error(err, label)
+			continue
+		}
+
+		if assert.NoError(err, label) {
+			assert.Equal("evcli-"+test.os+"-"+test.arch, build.AssetName,
+				label)
+			assert.Equal(test.uri, build.URI, label)
+			assert.Equal(test.hash, build.Checksum, label)
+		}
+	}
+
+	emptyIndex, err := DecodeUpdateIndex([]byte(`{"builds": []}`), indexURI)
+	require.NoError(err)
+
+	_, err = emptyIndex.LastBuildId()
+	assert.Error(err)
+}
+
+func generateTestReleaseKey(t *testing.T) (string, ed25519.PrivateKey) {
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	return base64.StdEncoding.EncodeToString(publicKey), privateKey
+}
@@ -0,0 +1,273 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+var ScratchpadFormats = []string{"json", "yaml", "dotenv"}
+
+func ScratchpadFormatFromPath(filePath string) string {
+	switch strings.ToLower(filepath.Ext(filePath)) {
+	case ".yaml", ".yml":
+		return "yaml"
+	case ".env":
+		return "dotenv"
+	default:
+		return "json"
+	}
+}
+
+func EncodeScratchpad(entries map[string]string, format string) ([]byte, error) {
+	switch format {
+	case "json":
+		data, err := json.MarshalIndent(entries, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+
+		return append(data, '\n'), nil
+
+	case "yaml":
+		return yaml.Marshal(entries)
+
+	case "dotenv":
+		return EncodeDotenv(entries), nil
+
+	default:
+		return nil, fmt.Errorf("unknown format %q", format)
+	}
+}
+
+func DecodeScratchpad(data []byte, format string) (map[string]string, error) {
+	var entries map[string]string
+
+	switch format {
+	case "json":
+		if err := json.Unmarshal(data, &entries); err != nil {
+			return nil, err
+		}
+
+	case "yaml":
+		if err := yaml.Unmarshal(data, &entries); err != nil {
+			return nil, err
+		}
+
+	case "dotenv":
+		return DecodeDotenv(data)
+
+	default:
+		return nil, fmt.Errorf("unknown format %q", format)
+	}
+
+	if entries == nil {
+		entries = make(map[string]string)
+	}
+
+	return entries, nil
+}
+
+func SortedScratchpadKeys(entries map[string]string) []string {
+	keys := make([]string, 0, len(entries))
+	for key := range entries {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
+
+// Values are always double-quoted in dotenv data, with backslash escape
+// sequences for double quotes, backslashes and control characters. This is
+// the subset of the syntax supported by most dotenv implementations.
+
+func EncodeDotenv(entries map[string]string) []byte {
+	var buf bytes.Buffer
+
+	for _, key := range SortedScratchpadKeys(entries) {
+		buf.WriteString(key)
+		buf.WriteString("=\"")
+
+		for _, c := range entries[key] {
+			switch c {
+			case '"':
+				buf.WriteString(`\"`)
+			case '\\':
+				buf.WriteString(`\\`)
+			case '\n':
+				buf.WriteString(`\n`)
+			case '\r':
+				buf.WriteString(`\r`)
+			case '\t':
+				buf.WriteString(`\t`)
+			default:
+				buf.WriteRune(c)
+			}
+		}
+
+		buf.WriteString("\"\n")
+	}
+
+	return buf.Bytes()
+}
+
+func DecodeDotenv(data []byte) (map[string]string, error) {
+	entries := make(map[string]string)
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(nil, len(data)+1)
+
+	lineNumber := 0
+
+	for scanner.Scan() {
+		lineNumber++
+
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '#' {
+			continue
+		}
+
+		line = strings.TrimPrefix(line, "export ")
+
+		parts := strings.SplitN(line, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("line %d: missing '='", lineNumber)
+		}
+
+		key := strings.TrimSpace(parts[0])
+		if key == "" {
+			return nil, fmt.Errorf("line %d: empty key", lineNumber)
+		}
+
+		value, err := decodeDotenvValue(strings.TrimSpace(parts[1]))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNumber, err)
+		}
+
+		entries[key] = value
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+func decodeDotenvValue(s string) (string, error) {
+	if s == "" {
+		return "", nil
+	}
+
+	switch s[0] {
+	case '\'':
+		if len(s) < 2 || s[len(s)-1] != '\'' {
+			return "", fmt.Errorf("unterminated single-quoted value")
+		}
+
+		return s[1 : len(s)-1], nil
+
+	case '"':
+		var buf bytes.Buffer
+
+		for i := 1; i < len(s); i++ {
+			c := s[i]
+
+			if c == '"' {
+				if i != len(s)-1 {
+					return "", fmt.Errorf("unexpected data after " +
+						"double-quoted value")
+				}
+
+				return buf.String(), nil
+			}
+
+			if c != '\\' {
+				buf.WriteByte(c)
+				continue
+			}
+
+			i++
+			if i >= len(s) {
+				break
+			}
+
+			switch s[i] {
+			case 'n':
+				buf.WriteByte('\n')
+			case 'r':
+				buf.WriteByte('\r')
+			case 't':
+				buf.WriteByte('\t')
+			default:
+				buf.WriteByte(s[i])
+			}
+		}
+
+		return "", fmt.Errorf("unterminated double-quoted value")
+
+	default:
+		return s, nil
+	}
+}
+
+type EnvironmentVariable struct {
+	Name  string
+	Value string
+}
+
+// ScratchpadEnvironment maps scratchpad entries to environment variables.
+// Keys are converted to uppercase and characters which are not valid in
+// variable names are replaced by underscores, so that the "build.commit-id"
+// key becomes the BUILD_COMMIT_ID variable.
+func ScratchpadEnvironment(entries map[string]string, prefix string) ([]EnvironmentVariable, error) {
+	vars := make([]EnvironmentVariable, 0, len(entries))
+	keys := make(map[string]string)
+
+	for _, key := range SortedScratchpadKeys(entries) {
+		name := EnvironmentVariableName(prefix + key)
+
+		if key2, found := keys[name]; found {
+			return nil, fmt.Errorf("keys %q and %q both map to environment "+
+				"variable %s", key2, key, name)
+		}
+		keys[name] = key
+
+		vars = append(vars, EnvironmentVariable{
+			Name:  name,
+			Value: entries[key],
+		})
+	}
+
+	return vars, nil
+}
+
+func EnvironmentVariableName(s string) string {
+	var buf bytes.Buffer
+
+	for i, c := range strings.ToUpper(s) {
+		if i == 0 && c >= '0' && c <= '9' {
+			buf.WriteByte('_')
+		}
+
+		if (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9') || c == '_' {
+			buf.WriteRune(c)
+		} else {
+			buf.WriteByte('_')
+		}
+	}
+
+	return buf.String()
+}
+
+func ShellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'"'"'`) + "'"
+}
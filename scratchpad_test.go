@@ -0,0 +1,86 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDotenv(t *testing.T) {
+	assert := assert.New(t)
+
+	entries := map[string]string{
+		"a":     "",
+		"b":     "hello world",
+		"c.d-e": "line 1\nline 2\t\"quoted\" \\ 'single'",
+	}
+
+	data := EncodeDotenv(entries)
+	assert.Equal(`a=""
+b="hello world"
+c.d-e="line 1\nline 2\t\"quoted\" \\ 'single'"
+`, string(data))
+
+	entries2, err := DecodeDotenv(data)
+	if assert.NoError(err) {
+		assert.Equal(entries, entries2)
+	}
+
+	entries2, err = DecodeDotenv([]byte(`
+# comment
+export FOO=bar
+BAR = 'a "b" \n'
+EMPTY=
+`))
+	if assert.NoError(err) {
+		assert.Equal(map[string]string{
+			"FOO":   "bar",
+			"BAR":   `a "b" \n`,
+			"EMPTY": "",
+		}, entries2)
+	}
+
+	_, err = DecodeDotenv([]byte("FOO"))
+	assert.Error(err)
+
+	_, err = DecodeDotenv([]byte(`FOO="bar`))
+	assert.Error(err)
+
+	_, err = DecodeDotenv([]byte(`FOO="bar" baz`))
+	assert.Error(err)
+}
+
+func TestScratchpadEnvironment(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal("FOO", EnvironmentVariableName("foo"))
+	assert.Equal("BUILD_COMMIT_ID", EnvironmentVariableName("build.commit-id"))
+	assert.Equal("_1A", EnvironmentVariableName("1a"))
+	assert.Equal("A__B", EnvironmentVariableName("a éb"))
+
+	vars, err := ScratchpadEnvironment(map[string]string{
+		"b":     "2",
+		"a.key": "1",
+	}, "sp_")
+	if assert.NoError(err) {
+		assert.Equal([]EnvironmentVariable{
+			{"SP_A_KEY", "1"},
+			{"SP_B", "2"},
+		}, vars)
+	}
+
+	_, err = ScratchpadEnvironment(map[string]string{
+		"a-b": "1",
+		"a.b": "2",
+	}, "")
+	assert.Error(err)
+}
+
+func TestShellQuote(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal(`''`, ShellQuote(""))
+	assert.Equal(`'foo bar'`, ShellQuote("foo bar"))
+	assert.Equal(`'$HOME "x"'`, ShellQuote(`$HOME "x"`))
+	assert.Equal(`'it'"'"'s'`, ShellQuote("it's"))
+}
@@ -0,0 +1,516 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+
+	"golang.org/x/term"
+)
+
+// Shell commands are executed in the same process as the shell, reusing its
+// application and API client: the API key and the current project are only
+// resolved once. Since go-program cannot parse a command line twice, a new
+// program is created for each command, and its exit function is replaced so
+// that errors return to the shell instead of terminating the process.
+//
+// Global options used to start the shell apply to all commands. Options
+// configuring the HTTP client cannot be changed for a single command.
+//
+// Commands cannot be interrupted without interrupting the shell: Ctrl-C
+// terminates the process as it would outside of the shell.
+//
+// The history of the shell is saved in ~/.cache/evcli/shell_history.
+
+const shellHistorySize = 100
+
+type Shell struct {
+	GlobalArgs []string
+
+	ProjectName string
+
+	spec        *CompletionSpec
+	terminal    *term.Terminal
+	io          *shellIO
+	historyPath string
+}
+
+type shellIO struct {
+	io.Reader
+	io.Writer
+}
+
+// shellExit is used as panic value by the exit function of programs created
+// for shell commands.
+type shellExit int
+
+// shellInputReader replaces Ctrl-C characters with Ctrl-U: the terminal
+// stops reading when it receives Ctrl-C, while shell users expect it to clear
+// the current line.
+type shellInputReader struct {
+	r io.Reader
+}
+
+func (r *shellInputReader) Read(data []byte) (int, error) {
+	n, err := r.r.Read(data)
+
+	for i := 0; i < n; i++ {
+		if data[i] == 0x03 {
+			data[i] = 0x15
+		}
+	}
+
+	return n, err
+}
+
+func NewShell() *Shell {
+	spec := registry.Spec
+
+	s := Shell{
+		GlobalArgs: shellGlobalArguments(spec, os.Args[1:]),
+
+		spec:        spec,
+		historyPath: path.Join(app.HomePath, ".cache", "evcli", "shell_history"),
+	}
+
+	return &s
+}
+
+// shellGlobalArguments returns the global options used to start the shell so
+// that they can be passed to all commands. Project options are excluded since
+// the current project can be changed with the "use" built-in command.
+func shellGlobalArguments(spec *CompletionSpec, args []string) []string {
+	var globalArgs []string
+
+	for len(args) > 0 {
+		arg := args[0]
+		if !strings.HasPrefix(arg, "-") {
+			break
+		}
+
+		option := findCompletionOption(spec.Options, strings.TrimLeft(arg, "-"))
+		if option == nil {
+			break
+		}
+
+		n := 1
+		if option.ValueName != "" {
+			n = 2
+		}
+
+		if n > len(args) {
+			break
+		}
+
+		if option.LongName != "project-id" &&
+			option.LongName != "project-name" {
+			globalArgs = append(globalArgs, args[:n]...)
+		}
+
+		args = args[n:]
+	}
+
+	return globalArgs
+}
+
+func (s *Shell) Run() error {
+	stdin := int(os.Stdin.Fd())
+
+	if !term.IsTerminal(stdin) {
+		return fmt.Errorf("standard input is not a terminal")
+	}
+
+	s.io = &shellIO{
+		Reader: &shellInputReader{r: os.Stdin},
+		Writer: os.Stdout,
+	}
+
+	s.terminal = term.NewTerminal(s.io, "")
+	s.terminal.AutoCompleteCallback = s.complete
+
+	if err := s.loadHistory(); err != nil {
+		p.Error("cannot load shell history: %v", err)
+	}
+
+	for {
+		s.terminal.SetPrompt(s.prompt())
+
+		line, err := s.readLine(stdin)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				fmt.Println()
+				return nil
+			}
+
+			return err
+		}
+
+		words, err := SplitCommandLine(line)
+		if err != nil {
+			p.Error("%v", err)
+			continue
+		} else if len(words) == 0 {
+			continue
+		}
+
+		if err := s.saveHistoryLine(line); err != nil {
+			p.Error("cannot save shell history: %v", err)
+		}
+
+		if err := s.execute(words); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+
+			p.Error("%v", err)
+		}
+	}
+}
+
+func (s *Shell) readLine(fd int) (string, error) {
+	state, err := term.MakeRaw(fd)
+	if err != nil {
+		return "", fmt.Errorf("cannot configure terminal: %w", err)
+	}
+	defer term.Restore(fd, state)
+
+	return s.terminal.ReadLine()
+}
+
+// loadHistory reads the last lines of the history file and feeds them to the
+// terminal, which does not provide any other way to populate its history. The
+// file is then rewritten with these lines only so that it does not grow
+// indefinitely.
+func (s *Shell) loadHistory() error {
+	data, err := ioutil.ReadFile(s.historyPath)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+
+		return fmt.Errorf("cannot read %s: %w", s.historyPath, err)
+	}
+
+	var lines []string
+	for _, line := range strings.Split(string(data), "\n") {
+		if line != "" && isPrintableHistoryLine(line) {
+			lines = append(lines, line)
+		}
+	}
+
+	if len(lines) > shellHistorySize {
+		lines = lines[len(lines)-shellHistorySize:]
+	}
+
+	if len(lines) == 0 {
+		return nil
+	}
+
+	reader, writer := s.io.Reader, s.io.Writer
+	defer func() {
+		s.io.Reader, s.io.Writer = reader, writer
+	}()
+
+	s.io.Reader = strings.NewReader(strings.Join(lines, "\r") + "\r")
+	s.io.Writer = ioutil.Discard
+
+	for range lines {
+		if _, err := s.terminal.ReadLine(); err != nil {
+			return fmt.Errorf("cannot load history line: %w", err)
+		}
+	}
+
+	data = []byte(strings.Join(lines, "\n") + "\n")
+	if err := ioutil.WriteFile(s.historyPath, data, 0600); err != nil {
+		return fmt.Errorf("cannot write %s: %w", s.historyPath, err)
+	}
+
+	return nil
+}
+
+func (s *Shell) saveHistoryLine(line string) error {
+	if !isPrintableHistoryLine(line) {
+		return nil
+	}
+
+	dirPath := filepath.Dir(s.historyPath)
+	if err := os.MkdirAll(dirPath, 0700); err != nil {
+		return fmt.Errorf("cannot create directory %s: %w", dirPath, err)
+	}
+
+	flags := os.O_WRONLY | os.O_CREATE | os.O_APPEND
+	file, err := os.OpenFile(s.historyPath, flags, 0600)
+	if err != nil {
+		return fmt.Errorf("cannot open %s: %w", s.historyPath, err)
+	}
+	defer file.Close()
+
+	if _, err := file.WriteString(line + "\n"); err != nil {
+		return fmt.Errorf("cannot write %s: %w", s.historyPath, err)
+	}
+
+	return nil
+}
+
+// isPrintableHistoryLine indicates whether a line can be fed to the terminal
+// without being interpreted as a key sequence.
+func isPrintableHistoryLine(line string) bool {
+	for _, c := range line {
+		if !unicode.IsPrint(c) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (s *Shell) prompt() string {
+	project := s.ProjectName
+	if project == "" {
+		project = app.Client.ProjectId
+	}
+
+	if project == "" {
+		return "evcli> "
+	}
+
+	return fmt.Sprintf("evcli (%s)> ", project)
+}
+
+func (s *Shell) execute(words []string) error {
+	switch words[0] {
+	case "exit", "quit":
+		return io.EOF
+
+	case "use":
+		return s.use(words[1:])
+
+	default:
+		s.executeCommand(words)
+		return nil
+	}
+}
+
+func (s *Shell) use(args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("usage: use <project>")
+	}
+
+	name := args[0]
+
+	project, err := app.Client.FetchProjectByName(name)
+	if err != nil {
+		return fmt.Errorf("cannot fetch project %q: %w", name, err)
+	}
+
+	s.ProjectName = project.Name
+
+	app.projectIdOption = &project.Id
+	app.projectNameOption = nil
+	app.Client.ProjectId = project.Id
+
+	return nil
+}
+
+// executeCommand executes a command with a new program. The global state
+// modified by the command is restored once it returns or exits.
+func (s *Shell) executeCommand(words []string) {
+	shellProgram, shellRegistry, shellArgs := p, registry, os.Args
+	appState, projectId := *app, app.Client.ProjectId
+
+	defer func() {
+		p, registry, os.Args = shellProgram, shellRegistry, shellArgs
+		*app, app.Client.ProjectId = appState, projectId
+
+		initializeOptions(app.Config)
+	}()
+
+	defer func() {
+		if value := recover(); value != nil {
+			if _, ok := value.(shellExit); !ok {
+				panic(value)
+			}
+		}
+	}()
+
+	newProgram()
+	p.Exit = func(code int) {
+		panic(shellExit(code))
+	}
+
+	args := append([]string{shellArgs[0]}, s.GlobalArgs...)
+	os.Args = append(args, words...)
+
+	expandCommandLineAlias(app.Config.Aliases)
+	addPluginCommand()
+
+	p.ParseCommandLine()
+
+	for _, name := range []string{"har", "ca-file", "client-cert",
+		"client-key", "insecure-skip-verify", "proxy"} {
+		if p.IsOptionSet(name) != shellProgram.IsOptionSet(name) ||
+			p.OptionValue(name) != shellProgram.OptionValue(name) {
+			p.Fatal("--%s cannot be changed in the shell", name)
+		}
+	}
+
+	if p.CommandName() == "shell" {
+		p.Fatal("cannot start a shell in the shell")
+	}
+
+	initializeOptions(app.Config)
+	initializeAppOptions()
+
+	p.Run()
+}
+
+func (s *Shell) complete(line string, pos int, key rune) (string, int, bool) {
+	if key != '\t' {
+		return "", 0, false
+	}
+
+	prefix := line[:pos]
+
+	words := strings.Fields(prefix)
+	if len(words) == 0 || strings.HasSuffix(prefix, " ") {
+		words = append(words, "")
+	}
+
+	current := words[len(words)-1]
+
+	var completions Completions
+
+	if len(words) == 2 && words[0] == "use" {
+		completions, _ = completeProjectNames(nil)
+		completions = completions.Filter(current)
+	} else {
+		// Errors are ignored: there is nothing useful we could do with them
+		// in the middle of a line being edited.
+		completions, _ = s.spec.Complete(words, completionSources())
+
+		if len(words) == 1 {
+			for _, name := range []string{"exit", "quit", "use"} {
+				if strings.HasPrefix(name, current) {
+					completions = append(completions, Completion{Value: name})
+				}
+			}
+		}
+	}
+
+	if len(completions) == 0 {
+		return "", 0, false
+	}
+
+	values := make([]string, len(completions))
+	for i, c := range completions {
+		values[i] = c.Value
+	}
+
+	sort.Strings(values)
+
+	value := values[0]
+
+	if len(values) == 1 {
+		if !strings.HasSuffix(value, "=") {
+			value += " "
+		}
+	} else {
+		value = commonPrefix(values)
+
+		if value == current {
+			fmt.Fprintf(s.terminal, "%s\n", strings.Join(values, "  "))
+			return "", 0, false
+		}
+	}
+
+	newPrefix := prefix[:len(prefix)-len(current)] + value
+
+	return newPrefix + line[pos:], len(newPrefix), true
+}
+
+func commonPrefix(ss []string) string {
+	prefix := ss[0]
+
+	for _, s := range ss[1:] {
+		for !strings.HasPrefix(s, prefix) {
+			prefix = prefix[:len(prefix)-1]
+		}
+	}
+
+	return prefix
+}
+
+// SplitCommandLine splits a line into words using a subset of the POSIX
+// shell syntax: words are separated by whitespaces, and can be quoted with
+// single quotes, double quotes or backslashes.
+func SplitCommandLine(s string) ([]string, error) {
+	var words []string
+	var word strings.Builder
+
+	inWord := false
+	var quote rune
+	escaped := false
+
+	for _, c := range s {
+		switch {
+		case escaped:
+			word.WriteRune(c)
+			escaped = false
+
+		case quote == '\'':
+			if c == '\'' {
+				quote = 0
+			} else {
+				word.WriteRune(c)
+			}
+
+		case quote == '"':
+			switch c {
+			case '"':
+				quote = 0
+			case '\\':
+				escaped = true
+			default:
+				word.WriteRune(c)
+			}
+
+		case c == '\'' || c == '"':
+			quote = c
+			inWord = true
+
+		case c == '\\':
+			escaped = true
+			inWord = true
+
+		case c == ' ' || c == '\t' || c == '\n':
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+
+		default:
+			word.WriteRune(c)
+			inWord = true
+		}
+	}
+
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated quoted string")
+	} else if escaped {
+		return nil, fmt.Errorf("unterminated escape sequence")
+	}
+
+	if inWord {
+		words = append(words, word.String())
+	}
+
+	return words, nil
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplitCommandLine(t *testing.T) {
+	assert := assert.New(t)
+
+	split := func(s string) []string {
+		words, err := SplitCommandLine(s)
+		if !assert.NoError(err, s) {
+			return nil
+		}
+
+		return words
+	}
+
+	assert.Nil(split(""))
+	assert.Nil(split("  \t "))
+	assert.Equal([]string{"a", "b"}, split(" a  b "))
+	assert.Equal([]string{"a b", "c"}, split(`'a b' c`))
+	assert.Equal([]string{`a "b"`}, split(`"a \"b\""`))
+	assert.Equal([]string{`a\b`}, split(`'a\b'`))
+	assert.Equal([]string{"a b"}, split(`a\ b`))
+	assert.Equal([]string{"ab", ""}, split(`a'b' ""`))
+
+	_, err := SplitCommandLine(`'a`)
+	assert.Error(err)
+
+	_, err = SplitCommandLine(`a\`)
+	assert.Error(err)
+}
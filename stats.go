@@ -0,0 +1,156 @@
+package main
+
+import (
+	"encoding/json"
+	"math"
+	"sort"
+	"time"
+)
+
+type PipelineStats struct {
+	Name         string
+	Count        int
+	StatusCounts map[string]int
+	SuccessRate  *float64
+	P50          *time.Duration
+	P90          *time.Duration
+	P99          *time.Duration
+	SlowestRuns  Pipelines
+}
+
+type PipelineStatsList []*PipelineStats
+
+func ComputePipelineStats(pipelines Pipelines, nbSlowestRuns int) PipelineStatsList {
+	groups := make(map[string]Pipelines)
+	for _, pipeline := range pipelines {
+		groups[pipeline.Name] = append(groups[pipeline.Name], pipeline)
+	}
+
+	names := make([]string, 0, len(groups))
+	for name := range groups {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	statsList := make(PipelineStatsList, len(names))
+	for i, name := range names {
+		statsList[i] = computePipelineGroupStats(name, groups[name],
+			nbSlowestRuns)
+	}
+
+	return statsList
+}
+
+func computePipelineGroupStats(name string, pipelines Pipelines, nbSlowestRuns int) *PipelineStats {
+	stats := PipelineStats{
+		Name:         name,
+		Count:        len(pipelines),
+		StatusCounts: make(map[string]int),
+	}
+
+	var finishedRuns Pipelines
+
+	for _, pipeline := range pipelines {
+		stats.StatusCounts[pipeline.Status]++
+
+		if pipeline.Duration() != nil {
+			finishedRuns = append(finishedRuns, pipeline)
+		}
+	}
+
+	nbSuccesses := stats.StatusCounts[PipelineStatusSuccessful]
+	nbFinished := nbSuccesses + stats.StatusCounts[PipelineStatusFailed] +
+		stats.StatusCounts[PipelineStatusAborted]
+	if nbFinished > 0 {
+		rate := float64(nbSuccesses) / float64(nbFinished)
+		stats.SuccessRate = &rate
+	}
+
+	sort.SliceStable(finishedRuns, func(i, j int) bool {
+		return *finishedRuns[i].Duration() < *finishedRuns[j].Duration()
+	})
+
+	durations := make([]time.Duration, len(finishedRuns))
+	for i, pipeline := range finishedRuns {
+		durations[i] = *pipeline.Duration()
+	}
+
+	stats.P50 = Percentile(durations, 50)
+	stats.P90 = Percentile(durations, 90)
+	stats.P99 = Percentile(durations, 99)
+
+	for i := len(finishedRuns) - 1; i >= 0; i-- {
+		if len(stats.SlowestRuns) >= nbSlowestRuns {
+			break
+		}
+
+		stats.SlowestRuns = append(stats.SlowestRuns, finishedRuns[i])
+	}
+
+	return &stats
+}
+
+// Percentile uses the nearest-rank method on a sorted list of durations.
+func Percentile(durations []time.Duration, p int) *time.Duration {
+	if len(durations) == 0 {
+		return nil
+	}
+
+	rank := int(math.Ceil(float64(p) / 100.0 * float64(len(durations))))
+	if rank < 1 {
+		rank = 1
+	}
+
+	d := durations[rank-1]
+	return &d
+}
+
+func (s *PipelineStats) MarshalJSON() ([]byte, error) {
+	type slowRun struct {
+		Id        string  `json:"id"`
+		EventTime string  `json:"event_time"`
+		Status    string  `json:"status"`
+		Duration  float64 `json:"duration"`
+	}
+
+	seconds := func(d *time.Duration) *float64 {
+		if d == nil {
+			return nil
+		}
+
+		s := d.Seconds()
+		return &s
+	}
+
+	slowestRuns := make([]slowRun, len(s.SlowestRuns))
+	for i, pipeline := range s.SlowestRuns {
+		slowestRuns[i] = slowRun{
+			Id:        pipeline.Id,
+			EventTime: pipeline.EventTime,
+			Status:    pipeline.Status,
+			Duration:  pipeline.Duration().Seconds(),
+		}
+	}
+
+	value := struct {
+		Name         string         `json:"name"`
+		Count        int            `json:"count"`
+		StatusCounts map[string]int `json:"status_counts"`
+		SuccessRate  *float64       `json:"success_rate"`
+		P50          *float64       `json:"p50"`
+		P90          *float64       `json:"p90"`
+		P99          *float64       `json:"p99"`
+		SlowestRuns  []slowRun      `json:"slowest_runs"`
+	}{
+		Name:         s.Name,
+		Count:        s.Count,
+		StatusCounts: s.StatusCounts,
+		SuccessRate:  s.SuccessRate,
+		P50:          seconds(s.P50),
+		P90:          seconds(s.P90),
+		P99:          seconds(s.P99),
+		SlowestRuns:  slowestRuns,
+	}
+
+	return json.Marshal(value)
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPercentile(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Nil(Percentile(nil, 50))
+
+	durations := make([]time.Duration, 100)
+	for i := range durations {
+		durations[i] = time.Duration(i+1) * time.Second
+	}
+
+	assert.Equal(50*time.Second, *Percentile(durations, 50))
+	assert.Equal(90*time.Second, *Percentile(durations, 90))
+	assert.Equal(99*time.Second, *Percentile(durations, 99))
+	assert.Equal(100*time.Second, *Percentile(durations, 100))
+	assert.Equal(1*time.Second, *Percentile(durations, 0))
+
+	durations = []time.Duration{time.Second, 3 * time.Second}
+	assert.Equal(time.Second, *Percentile(durations, 50))
+	assert.Equal(3*time.Second, *Percentile(durations, 90))
+}
+
+func TestComputePipelineStats(t *testing.T) {
+	assert := assert.New(t)
+
+	start := time.Date(2022, 7, 20, 12, 0, 0, 0, time.UTC)
+
+	pipeline := func(id, name, status string, seconds int) *Pipeline {
+		p := Pipeline{Id: id, Name: name, Status: status}
+
+		if seconds > 0 {
+			end := start.Add(time.Duration(seconds) * time.Second)
+			p.StartTime = &start
+			p.EndTime = &end
+		}
+
+		return &p
+	}
+
+	pipelines := Pipelines{
+		pipeline("1", "b", "successful", 10),
+		pipeline("2", "a", "failed", 30),
+		pipeline("3", "b", "successful", 20),
+		pipeline("4", "b", "failed", 40),
+		pipeline("5", "b", PipelineStatusStarted, 0),
+		pipeline("6", "b", "aborted", 5),
+	}
+
+	statsList := ComputePipelineStats(pipelines, 2)
+	if assert.Len(statsList, 2) {
+		a := statsList[0]
+		assert.Equal("a", a.Name)
+		assert.Equal(1, a.Count)
+		assert.Equal(0.0, *a.SuccessRate)
+
+		b := statsList[1]
+		assert.Equal("b", b.Name)
+		assert.Equal(5, b.Count)
+		assert.Equal(map[string]int{
+			PipelineStatusSuccessful: 2,
+			PipelineStatusFailed:     1,
+			PipelineStatusStarted:    1,
+			PipelineStatusAborted:    1,
+		}, b.StatusCounts)
+		assert.Equal(0.5, *b.SuccessRate)
+		assert.Equal(10*time.Second, *b.P50)
+		assert.Equal(40*time.Second, *b.P99)
+		if assert.Len(b.SlowestRuns, 2) {
+			assert.Equal("4", b.SlowestRuns[0].Id)
+			assert.Equal("3", b.SlowestRuns[1].Id)
+		}
+	}
+}
@@ -0,0 +1,3 @@
+module github.com/exograd/go-program
+
+go 1.17
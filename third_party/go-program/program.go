@@ -0,0 +1,121 @@
+// Copyright (c) 2021 Nicolas Martyanoff <khaelin@gmail.com>
+// Copyright (c) 2022 Exograd SAS.
+//
+// Permission to use, copy, modify, and distribute this software for any
+// purpose with or without fee is hereby granted, provided that the above
+// copyright notice and this permission notice appear in all copies.
+//
+// THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+// WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+// MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY
+// SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+// WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+// ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF OR
+// IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+
+package program
+
+import (
+	"fmt"
+	"os"
+)
+
+type Main func(*Program)
+
+type Program struct {
+	Name        string
+	Description string
+	Main        Main
+
+	commands  map[string]*Command
+	options   map[string]*Option
+	arguments []*Argument
+
+	command *Command
+
+	quiet      bool
+	debugLevel int
+
+	// Exit is called to terminate the program on errors and after printing
+	// help. It defaults to os.Exit and must not return.
+	Exit func(int)
+}
+
+func NewProgram(name, description string) *Program {
+	p := &Program{
+		Name:        name,
+		Description: description,
+
+		commands: make(map[string]*Command),
+
+		options: make(map[string]*Option),
+
+		Exit: os.Exit,
+	}
+
+	p.addDefaultOptions()
+
+	return p
+}
+
+func (p *Program) SetMain(main Main) {
+	if len(p.commands) > 0 {
+		panic("cannot have a main function with commands")
+	}
+
+	p.Main = main
+}
+
+func (p *Program) Run() {
+	var main Main
+	if p.command == nil {
+		if p.Main == nil {
+			panic("missing main function")
+		}
+
+		main = p.Main
+	} else {
+		main = p.command.Main
+	}
+
+	main(p)
+}
+
+func (p *Program) Debug(level int, format string, args ...interface{}) {
+	if level > p.debugLevel {
+		return
+	}
+
+	fmt.Fprintf(os.Stderr, format+"\n", args...)
+}
+
+func (p *Program) Info(format string, args ...interface{}) {
+	if p.quiet {
+		return
+	}
+
+	fmt.Fprintf(os.Stderr, format+"\n", args...)
+}
+
+func (p *Program) Error(format string, args ...interface{}) {
+	fmt.Fprintf(os.Stderr, "error: "+format+"\n", args...)
+}
+
+func (p *Program) Fatal(format string, args ...interface{}) {
+	p.Error(format, args...)
+	p.Exit(1)
+}
+
+func (p *Program) fatal(format string, args ...interface{}) {
+	p.Error(format, args...)
+
+	fmt.Fprintf(os.Stderr, "\n")
+
+	if p.command == nil {
+		p.PrintUsage(nil)
+	} else {
+		p.PrintUsage(p.command)
+	}
+
+	p.Exit(1)
+}
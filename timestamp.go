@@ -0,0 +1,215 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Timestamps are used for event times and for all time range filters. The
+// following formats are supported:
+//
+// - "now";
+// - RFC 3339 date times, e.g. "2022-07-20T14:00:00Z";
+// - Unix timestamps, optionally prefixed by '@', e.g. "1658325600";
+// - relative times, e.g. "-2h", "+30m", "-1d12h" or "3w ago";
+// - "today", "yesterday" and "tomorrow", optionally followed by a time of
+//   the day, e.g. "yesterday 14:00";
+// - a time of the day, e.g. "14:00" or "14:00:30", for the current day;
+// - local dates and date times, e.g. "2022-07-20", "2022-07-20 14:00" or
+//   "2022-07-20T14:00:30".
+//
+// Local dates and times are interpreted in the location passed to
+// ParseTimestamp.
+
+var durationRE = regexp.MustCompile(`^(?:\d+(?:\.\d+)?(?:w|d|h|m|s|ms))+$`)
+var durationPartRE = regexp.MustCompile(`(\d+(?:\.\d+)?)(w|d|h|ms|m|s)`)
+
+var localTimeLayouts = []string{
+	"2006-01-02",
+	"2006-01-02 15:04",
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02T15:04:05",
+}
+
+func ParseTimestamp(s string, now time.Time, location *time.Location) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	now = now.In(location)
+
+	if s == "" {
+		return time.Time{}, fmt.Errorf("empty timestamp")
+	}
+
+	if s == "now" {
+		return now, nil
+	}
+
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+
+	if t, ok := parseUnixTimestamp(s); ok {
+		return t, nil
+	}
+
+	if s[0] == '-' || s[0] == '+' {
+		d, err := ParseDuration(s[1:])
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid relative time %q: %w",
+				s, err)
+		}
+
+		if s[0] == '-' {
+			d = -d
+		}
+
+		return now.Add(d), nil
+	}
+
+	if strings.HasSuffix(s, " ago") {
+		ds := strings.TrimSpace(strings.TrimSuffix(s, " ago"))
+
+		d, err := ParseDuration(ds)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid relative time %q: %w",
+				s, err)
+		}
+
+		return now.Add(-d), nil
+	}
+
+	if t, ok, err := parseDayTimestamp(s, now); ok {
+		return t, err
+	}
+
+	for _, layout := range localTimeLayouts {
+		if t, err := time.ParseInLocation(layout, s, location); err == nil {
+			return t, nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("invalid timestamp %q", s)
+}
+
+// ParseDuration extends time.ParseDuration with day ("d") and week ("w")
+// units. Days are always 24 hours long.
+func ParseDuration(s string) (time.Duration, error) {
+	if !durationRE.MatchString(s) {
+		return 0, fmt.Errorf("invalid duration %q", s)
+	}
+
+	var d time.Duration
+
+	for _, match := range durationPartRE.FindAllStringSubmatch(s, -1) {
+		value, err := strconv.ParseFloat(match[1], 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration %q", s)
+		}
+
+		var unit time.Duration
+
+		switch match[2] {
+		case "w":
+			unit = 7 * 24 * time.Hour
+		case "d":
+			unit = 24 * time.Hour
+		case "h":
+			unit = time.Hour
+		case "m":
+			unit = time.Minute
+		case "s":
+			unit = time.Second
+		case "ms":
+			unit = time.Millisecond
+		}
+
+		d += time.Duration(value * float64(unit))
+	}
+
+	return d, nil
+}
+
+func parseUnixTimestamp(s string) (time.Time, bool) {
+	s = strings.TrimPrefix(s, "@")
+
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return time.Time{}, false
+		}
+	}
+
+	i, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	return time.Unix(i, 0).UTC(), true
+}
+
+func parseDayTimestamp(s string, now time.Time) (time.Time, bool, error) {
+	parts := strings.Fields(s)
+
+	var day time.Time
+	var timeString string
+	var timeOnly bool
+
+	midnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0,
+		now.Location())
+
+	switch parts[0] {
+	case "today":
+		day = midnight
+	case "yesterday":
+		day = midnight.AddDate(0, 0, -1)
+	case "tomorrow":
+		day = midnight.AddDate(0, 0, 1)
+
+	default:
+		if len(parts) != 1 {
+			return time.Time{}, false, nil
+		}
+
+		day = midnight
+		timeString = parts[0]
+		timeOnly = true
+	}
+
+	if len(parts) > 2 {
+		return time.Time{}, true, fmt.Errorf("invalid timestamp %q", s)
+	} else if len(parts) == 2 {
+		timeString = parts[1]
+	}
+
+	if timeString == "" {
+		return day, true, nil
+	}
+
+	var tod time.Time
+	var err error
+
+	for _, layout := range []string{"15:04", "15:04:05"} {
+		tod, err = time.Parse(layout, timeString)
+		if err == nil {
+			break
+		}
+	}
+
+	if err != nil {
+		if timeOnly {
+			// Not a time of the day at all, let the caller try other
+			// formats.
+			return time.Time{}, false, nil
+		}
+
+		return time.Time{}, true, fmt.Errorf("invalid time of the day %q",
+			timeString)
+	}
+
+	t := time.Date(day.Year(), day.Month(), day.Day(),
+		tod.Hour(), tod.Minute(), tod.Second(), 0, day.Location())
+
+	return t, true, nil
+}
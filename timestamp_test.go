@@ -0,0 +1,102 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTimestamp(t *testing.T) {
+	assert := assert.New(t)
+
+	location, err := time.LoadLocation("Europe/Paris")
+	require.NoError(t, err)
+
+	now := time.Date(2022, 7, 20, 12, 30, 0, 0, time.UTC)
+
+	parse := func(s string) time.Time {
+		t.Helper()
+
+		ts, err := ParseTimestamp(s, now, location)
+		require.NoError(t, err, s)
+
+		return ts
+	}
+
+	assertTime := func(expected time.Time, s string) {
+		t.Helper()
+		assert.True(expected.Equal(parse(s)), "%s: expected %v, got %v",
+			s, expected, parse(s))
+	}
+
+	assertTime(now, "now")
+	assertTime(time.Date(2022, 7, 1, 10, 0, 0, 0, time.UTC),
+		"2022-07-01T10:00:00Z")
+	assertTime(time.Date(2022, 7, 1, 10, 0, 0, 0, time.UTC),
+		"1656669600")
+	assertTime(time.Date(2022, 7, 1, 10, 0, 0, 0, time.UTC),
+		"@1656669600")
+
+	assertTime(now.Add(-2*time.Hour), "-2h")
+	assertTime(now.Add(90*time.Minute), "+1h30m")
+	assertTime(now.Add(-36*time.Hour), "-1d12h")
+	assertTime(now.Add(-14*24*time.Hour), "2w ago")
+
+	// 12:30 UTC is 14:30 in Paris (CEST)
+	assertTime(time.Date(2022, 7, 20, 0, 0, 0, 0, location), "today")
+	assertTime(time.Date(2022, 7, 19, 14, 0, 0, 0, location),
+		"yesterday 14:00")
+	assertTime(time.Date(2022, 7, 21, 8, 15, 30, 0, location),
+		"tomorrow 08:15:30")
+	assertTime(time.Date(2022, 7, 20, 9, 0, 0, 0, location), "09:00")
+
+	assertTime(time.Date(2022, 7, 14, 0, 0, 0, 0, location), "2022-07-14")
+	assertTime(time.Date(2022, 7, 14, 18, 5, 0, 0, location),
+		"2022-07-14 18:05")
+	assertTime(time.Date(2022, 7, 14, 18, 5, 10, 0, location),
+		"2022-07-14T18:05:10")
+
+	for _, s := range []string{
+		"", "-", "-2x", "yesterday 25:00", "today at noon", "2022-13-01",
+		"foo",
+	} {
+		_, err := ParseTimestamp(s, now, location)
+		assert.Error(err, s)
+	}
+}
+
+func TestParseDuration(t *testing.T) {
+	assert := assert.New(t)
+
+	d, err := ParseDuration("24h")
+	if assert.NoError(err) {
+		assert.Equal(24*time.Hour, d)
+	}
+
+	d, err = ParseDuration("1w2d3h4m5s")
+	if assert.NoError(err) {
+		assert.Equal(9*24*time.Hour+3*time.Hour+4*time.Minute+5*time.Second,
+			d)
+	}
+
+	d, err = ParseDuration("1.5h")
+	if assert.NoError(err) {
+		assert.Equal(90*time.Minute, d)
+	}
+
+	d, err = ParseDuration("250ms")
+	if assert.NoError(err) {
+		assert.Equal(250*time.Millisecond, d)
+	}
+
+	_, err = ParseDuration("")
+	assert.Error(err)
+
+	_, err = ParseDuration("12")
+	assert.Error(err)
+
+	_, err = ParseDuration("3y")
+	assert.Error(err)
+}
@@ -0,0 +1,220 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Request and response bodies are only read when tracing or recording a HAR
+// archive. Since they can be arbitrarily large (e.g. scratchpad values read
+// from a file), at most maxCapturedBodySize bytes are kept in memory; the
+// rest of the body is streamed as usual. Bodies which are not text are never
+// printed or recorded.
+
+const maxCapturedBodySize = 64 * 1024
+
+type CapturedBody struct {
+	Data        []byte
+	Truncated   bool
+	ContentType string
+}
+
+func (b *CapturedBody) IsEmpty() bool {
+	return len(b.Data) == 0
+}
+
+// IsText indicates whether the body can be printed or recorded as text.
+func (b *CapturedBody) IsText() bool {
+	mediaType := b.ContentType
+	if i := strings.IndexByte(mediaType, ';'); i >= 0 {
+		mediaType = mediaType[:i]
+	}
+	mediaType = strings.ToLower(strings.TrimSpace(mediaType))
+
+	text := mediaType == "" || strings.HasPrefix(mediaType, "text/")
+	for _, s := range []string{"json", "xml", "yaml", "x-www-form-urlencoded"} {
+		if strings.Contains(mediaType, s) {
+			text = true
+		}
+	}
+
+	return text && utf8.Valid(b.Data)
+}
+
+// Text returns the content of a text body with sensitive values redacted.
+func (b *CapturedBody) Text() string {
+	return string(RedactBody(b.Data, b.ContentType))
+}
+
+// Size returns the size of the body, or -1 if it is unknown.
+func (b *CapturedBody) Size(contentLength int64) int {
+	if !b.Truncated {
+		return len(b.Data)
+	} else if contentLength >= 0 {
+		return int(contentLength)
+	}
+
+	return -1
+}
+
+func captureRequestBody(req *http.Request) (*CapturedBody, error) {
+	contentType := req.Header.Get("Content-Type")
+
+	if req.Body == nil || req.Body == http.NoBody {
+		return &CapturedBody{ContentType: contentType}, nil
+	}
+
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		defer body.Close()
+
+		data, err := readBodyPrefix(body)
+		if err != nil {
+			return nil, err
+		}
+
+		return newCapturedBody(data, contentType), nil
+	}
+
+	data, err := readBodyPrefix(req.Body)
+	if err != nil {
+		req.Body.Close()
+		return nil, err
+	}
+
+	req.Body = prependBody(data, req.Body)
+
+	return newCapturedBody(data, contentType), nil
+}
+
+func captureResponseBody(res *http.Response) (*CapturedBody, error) {
+	data, err := readBodyPrefix(res.Body)
+	if err != nil {
+		res.Body.Close()
+		return nil, err
+	}
+
+	res.Body = prependBody(data, res.Body)
+
+	return newCapturedBody(data, res.Header.Get("Content-Type")), nil
+}
+
+// readBodyPrefix reads one byte more than the maximum size of captured bodies
+// so that truncated bodies can be detected.
+func readBodyPrefix(body io.Reader) ([]byte, error) {
+	return ioutil.ReadAll(io.LimitReader(body, maxCapturedBodySize+1))
+}
+
+func newCapturedBody(data []byte, contentType string) *CapturedBody {
+	b := CapturedBody{
+		Data:        data,
+		ContentType: contentType,
+	}
+
+	if len(data) > maxCapturedBodySize {
+		b.Data = data[:maxCapturedBodySize]
+		b.Truncated = true
+
+		// Do not split the last character of text bodies
+		for i := 1; i < utf8.UTFMax && i <= len(b.Data); i++ {
+			if utf8.RuneStart(b.Data[len(b.Data)-i]) {
+				if !utf8.FullRune(b.Data[len(b.Data)-i:]) {
+					b.Data = b.Data[:len(b.Data)-i]
+				}
+
+				break
+			}
+		}
+	}
+
+	return &b
+}
+
+type prependedBody struct {
+	io.Reader
+	io.Closer
+}
+
+func prependBody(data []byte, body io.ReadCloser) io.ReadCloser {
+	return &prependedBody{
+		Reader: io.MultiReader(bytes.NewReader(data), body),
+		Closer: body,
+	}
+}
+
+func TraceRequest(req *http.Request, body *CapturedBody) {
+	var buf bytes.Buffer
+
+	fmt.Fprintf(&buf, "%s %s %s\n", req.Method, req.URL.String(), req.Proto)
+	writeTraceHeader(&buf, req.Header)
+	writeTraceBody(&buf, body)
+
+	writeTrace("> ", buf.String())
+}
+
+func TraceResponse(res *http.Response, body *CapturedBody, d time.Duration) {
+	var buf bytes.Buffer
+
+	fmt.Fprintf(&buf, "%s %s (%s)\n", res.Proto, res.Status,
+		FormatRequestDuration(d))
+	writeTraceHeader(&buf, res.Header)
+	writeTraceBody(&buf, body)
+
+	writeTrace("< ", buf.String())
+}
+
+func writeTraceHeader(buf *bytes.Buffer, header http.Header) {
+	if len(header) > 0 {
+		buf.WriteString(FormatHeader(header, ""))
+		buf.WriteByte('\n')
+	}
+}
+
+func writeTraceBody(buf *bytes.Buffer, body *CapturedBody) {
+	if body.IsEmpty() {
+		return
+	}
+
+	buf.WriteByte('\n')
+
+	if !body.IsText() {
+		buf.WriteString("[binary data]\n")
+		return
+	}
+
+	text := body.Text()
+	buf.WriteString(text)
+	if text[len(text)-1] != '\n' {
+		buf.WriteByte('\n')
+	}
+
+	if body.Truncated {
+		fmt.Fprintf(buf, "[truncated to %d bytes]\n", len(body.Data))
+	}
+}
+
+func writeTrace(prefix, s string) {
+	var buf bytes.Buffer
+
+	for _, line := range strings.Split(strings.TrimSuffix(s, "\n"), "\n") {
+		if line == "" {
+			buf.WriteString(strings.TrimSpace(prefix))
+		} else {
+			buf.WriteString(prefix)
+			buf.WriteString(line)
+		}
+		buf.WriteByte('\n')
+	}
+
+	os.Stderr.Write(buf.Bytes())
+}
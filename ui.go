@@ -20,6 +20,15 @@ var (
 	ColorWhite   = Color(7)
 )
 
+func IsTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
 func Confirm(prompt string) bool {
 	if skipConfirmations {
 		return true
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+
+	"github.com/exograd/go-program"
+)
+
+// The update check runs in the background while the command is executed. The
+// last known build id is cached so that the network is queried at most once
+// a day; the check time is written before querying the network so that evcli
+// does not keep trying (and failing) when there is no network access, or when
+// commands exit before the check completes.
+//
+// Commands never wait for the check: if it has not completed when the
+// command exits, the notice is printed based on the result of the previous
+// check.
+
+const (
+	updateCheckInterval = 24 * time.Hour
+	updateCheckTimeout  = 2 * time.Second
+)
+
+type UpdateCheckCache struct {
+	LastBuildId string    `json:"last_build_id,omitempty"`
+	CheckTime   time.Time `json:"check_time"`
+}
+
+type UpdateCheck struct {
+	cachedBuildId *program.BuildId
+	result        chan *program.BuildId
+}
+
+func (a *App) StartUpdateCheck() *UpdateCheck {
+	check := UpdateCheck{
+		result: make(chan *program.BuildId, 1),
+	}
+
+	cache, err := a.loadUpdateCheckCache()
+	if err != nil {
+		p.Debug(1, "cannot load update check cache: %v", err)
+	}
+
+	if cache == nil {
+		cache = &UpdateCheckCache{}
+	}
+
+	check.cachedBuildId = a.cachedNewerBuildId(cache)
+
+	now := time.Now()
+	if now.Sub(cache.CheckTime) < updateCheckInterval {
+		check.result <- check.cachedBuildId
+		return &check
+	}
+
+	cache.CheckTime = now
+	if err := a.writeUpdateCheckCache(cache); err != nil {
+		p.Debug(1, "cannot write update check cache: %v", err)
+	}
+
+	go func() {
+		// The update check is not part of the command being executed: it
+		// must never be able to crash the program.
+		defer func() {
+			if value := recover(); value != nil {
+				p.Debug(1, "update check failed: %v", value)
+				check.result <- nil
+			}
+		}()
+
+		check.result <- a.checkForUpdate(cache)
+	}()
+
+	return &check
+}
+
+// PrintNotice prints a message on stderr if a new build is available. If the
+// check is still running, the result of the previous check is used.
+func (check *UpdateCheck) PrintNotice() {
+	var newBuildId *program.BuildId
+
+	select {
+	case newBuildId = <-check.result:
+	default:
+		p.Debug(1, "update check still running, using cached result")
+		newBuildId = check.cachedBuildId
+	}
+
+	if newBuildId == nil || !IsTerminal(os.Stderr) {
+		return
+	}
+
+	fmt.Fprintf(os.Stderr, "\nevcli %v is now available: run \"evcli update\" "+
+		"to install it\n", newBuildId)
+}
+
+func (a *App) checkForUpdate(cache *UpdateCheckCache) *program.BuildId {
+	ctx, cancel := context.WithDeadline(context.Background(),
+		cache.CheckTime.Add(updateCheckTimeout))
+	defer cancel()
+
+	p.Debug(1, "looking for the last build")
+
+	lastBuildId, err := a.lastBuildId(ctx)
+	if err != nil {
+		p.Debug(1, "cannot retrieve last build id: %v", err)
+		return a.cachedNewerBuildId(cache)
+	}
+
+	if lastBuildId == nil {
+		p.Debug(1, "no build available")
+		cache.LastBuildId = ""
+	} else {
+		cache.LastBuildId = lastBuildId.String()
+	}
+
+	if err := a.writeUpdateCheckCache(cache); err != nil {
+		p.Debug(1, "cannot write update check cache: %v", err)
+	}
+
+	return a.cachedNewerBuildId(cache)
+}
+
+func (a *App) cachedNewerBuildId(cache *UpdateCheckCache) *program.BuildId {
+	if cache.LastBuildId == "" {
+		return nil
+	}
+
+	var lastBuildId program.BuildId
+	if err := lastBuildId.Parse(cache.LastBuildId); err != nil {
+		p.Debug(1, "invalid cached build id %q: %v", cache.LastBuildId, err)
+		return nil
+	}
+
+	newBuildId, err := a.newerBuildId(&lastBuildId, false)
+	if err != nil {
+		p.Debug(1, "%v", err)
+		return nil
+	}
+
+	return newBuildId
+}
+
+func (a *App) loadUpdateCheckCache() (*UpdateCheckCache, error) {
+	filePath := a.updateCheckCachePath()
+
+	data, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+
+		return nil, fmt.Errorf("cannot read %s: %w", filePath, err)
+	}
+
+	var cache UpdateCheckCache
+	if err := json.Unmarshal(data, &cache); err != nil {
+		return nil, fmt.Errorf("cannot decode %s: %w", filePath, err)
+	}
+
+	return &cache, nil
+}
+
+func (a *App) writeUpdateCheckCache(cache *UpdateCheckCache) error {
+	filePath := a.updateCheckCachePath()
+
+	data, err := json.Marshal(cache)
+	if err != nil {
+		return fmt.Errorf("cannot encode cache: %w", err)
+	}
+
+	dirPath := filepath.Dir(filePath)
+	if err := os.MkdirAll(dirPath, 0700); err != nil {
+		return fmt.Errorf("cannot create directory %s: %w", dirPath, err)
+	}
+
+	// The process may exit while the cache is being written: use a
+	// temporary file to make sure it is never left truncated.
+	tmpPath := filePath + ".tmp"
+
+	if err := ioutil.WriteFile(tmpPath, data, 0600); err != nil {
+		return fmt.Errorf("cannot write %s: %w", tmpPath, err)
+	}
+
+	if err := os.Rename(tmpPath, filePath); err != nil {
+		return fmt.Errorf("cannot rename %s to %s: %w", tmpPath, filePath, err)
+	}
+
+	return nil
+}
+
+func (a *App) updateCheckCachePath() string {
+	return path.Join(a.HomePath, ".cache", "evcli", "update-check.json")
+}